@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/efecankaya/go-port-scanner/internal/config"
+	"github.com/efecankaya/go-port-scanner/internal/output"
+	"github.com/efecankaya/go-port-scanner/internal/ports"
+	"github.com/efecankaya/go-port-scanner/internal/resolver"
+	"github.com/efecankaya/go-port-scanner/internal/targets"
+)
+
+// openOutputs creates a writer for every sink, report formats without a file
+// are written to their default file. The returned function closes the opened
+// files.
+func openOutputs(sinks []config.Output) (output.Writer, func(), error) {
+	var (
+		writers []output.Writer
+		files   []*os.File
+	)
+	close_files := func() {
+		for _, file := range files {
+			file.Close()
+		}
+	}
+	for _, sink := range sinks {
+		format := sink.Format
+		if format == "" {
+			format = "text"
+		}
+		if sink.File == "" {
+			sink.File = reportFiles[format] //Reports go to their default file rather than the terminal
+		}
+		var dest io.Writer = os.Stdout
+		if sink.File != "" {
+			file, err := os.Create(sink.File)
+			if err != nil {
+				close_files()
+				return nil, nil, fmt.Errorf("creating output file: %w", err)
+			}
+			files = append(files, file)
+			dest = file
+		}
+		writer, err := output.New(format, dest)
+		if err != nil {
+			close_files()
+			return nil, nil, err
+		}
+		writers = append(writers, writer)
+	}
+	return output.Multi(writers...), close_files, nil
+}
+
+// reportFiles are the output formats meant to be read from a file rather than
+// the terminal, with the file they are written to when -of is not given.
+var reportFiles = map[string]string{"html": "report.html"}
+
+// reportFileArg lets a report format take its file right after the format, as
+// in -o html report.html. When -of is not set the first positional argument
+// is returned if it has the extension of the format, and the flags following
+// it are parsed again. Other arguments that look like report files are refused
+// rather than scanned as domain names. It returns an empty file when none was
+// given this way.
+func reportFileArg(format string) (string, error) {
+	default_file, ok := reportFiles[format]
+	if !ok || flag.Lookup("of").Value.String() != "" {
+		return "", nil
+	}
+	is_report := func(arg string) bool {
+		ext := strings.ToLower(filepath.Ext(arg))
+		return ext == filepath.Ext(default_file) || ext == ".htm"
+	}
+	var file string
+	if args := flag.Args(); len(args) > 0 && is_report(args[0]) {
+		file = args[0]
+		if err := flag.CommandLine.Parse(args[1:]); err != nil {
+			return "", err
+		}
+	}
+	for _, arg := range flag.Args() {
+		if is_report(arg) {
+			return "", fmt.Errorf("%s looks like a report file, give it with -of", arg)
+		}
+	}
+	return file, nil
+}
+
+// targetSpecs gathers target specifications from positional arguments, comma
+// separated flag values and a file. A "-" argument or file reads stdin.
+func targetSpecs(args []string, ip_list, domain_list, file string) ([]string, error) {
+	var specs []string
+	for _, arg := range args {
+		if arg == "-" {
+			stdin_specs, err := targets.ReadFile("-")
+			if err != nil {
+				return nil, err
+			}
+			specs = append(specs, stdin_specs...)
+			continue
+		}
+		specs = append(specs, targets.Split(arg)...)
+	}
+	specs = append(specs, targets.Split(ip_list)...)
+	specs = append(specs, targets.Split(domain_list)...)
+	if file != "" {
+		file_specs, err := targets.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, file_specs...)
+	}
+	return specs, nil
+}
+
+// resolveTargets validates target specifications and replaces domain names
+// with the addresses res finds for them. Addresses and ranges are kept as
+// given. The answers of every domain name are returned too.
+func resolveTargets(res *resolver.Resolver, specs []string) ([]string, []resolver.Answer, error) {
+	var names []string
+	for _, spec := range specs {
+		target, err := targets.Parse(spec)
+		if err != nil {
+			return nil, nil, err
+		}
+		if target.Host != "" {
+			names = append(names, target.Host)
+		}
+	}
+	answers := res.LookupAll(context.Background(), names)
+
+	var resolved []string
+	next := 0 //Answer of the next domain name
+	for _, spec := range specs {
+		if target, _ := targets.Parse(spec); target.Host == "" {
+			resolved = append(resolved, spec)
+			continue
+		}
+		answer := answers[next]
+		next++
+		if answer.Err != nil {
+			fmt.Fprintf(os.Stderr, "No such domain found! ==> %s\n", spec)
+			continue
+		}
+		resolved = append(resolved, answer.Addresses...)
+	}
+	return resolved, answers, nil
+}
+
+// parseRecordTypes parses the -dns-type flag into the A and AAAA selection.
+func parseRecordTypes(value string) (ipv4, ipv6 bool, err error) {
+	for _, record := range strings.Split(value, ",") {
+		switch strings.ToUpper(strings.TrimSpace(record)) {
+		case "A":
+			ipv4 = true
+		case "AAAA":
+			ipv6 = true
+		case "BOTH", "ALL":
+			ipv4, ipv6 = true, true
+		default:
+			return false, false, fmt.Errorf("unknown record type %q", record)
+		}
+	}
+	return ipv4, ipv6, nil
+}
+
+// portSetNames lists the named port sets for the usage text.
+func portSetNames() []string {
+	names := make([]string, 0, len(ports.Sets))
+	for name := range ports.Sets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
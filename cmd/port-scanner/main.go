@@ -1,176 +1,372 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"flag"
 	"fmt"
 	"net"
 	"os"
-	"slices"
+	"os/signal"
 	"strconv"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
 
-	"github.com/efecankaya/go-port-scanner/internal/scanner"
-	"github.com/efecankaya/go-port-scanner/internal/utils"
+	"github.com/efecankaya/go-port-scanner/internal/config"
+	"github.com/efecankaya/go-port-scanner/internal/output"
+	"github.com/efecankaya/go-port-scanner/internal/ports"
+	"github.com/efecankaya/go-port-scanner/internal/report"
+	"github.com/efecankaya/go-port-scanner/internal/resolver"
+	"github.com/efecankaya/go-port-scanner/internal/targets"
+	"github.com/efecankaya/go-port-scanner/scanner"
 	"github.com/fatih/color"
 )
 
 func main() {
 	welcome_print := color.New(color.FgCyan, color.Bold)
-	welcome_print.Print("  ______   ______    ____    _____                          ______\n /_  __/  / ____/   / __ \\  / ___/  _____  ____ _   ____   / ____/  ____ \n  / /    / /       / /_/ /  \\__ \\  / ___/ / __ `/  / __ \\ / / __   / __ \\\n / /    / /___    / ____/  ___/ / / /__  / /_/ /  / / / // /_/ /  / /_/ /\n/_/     \\____/   /_/      /____/  \\___/  \\__,_/  /_/ /_/ \\____/   \\____/\n")
+	welcome_print.Fprint(os.Stderr, "  ______   ______    ____    _____                          ______\n /_  __/  / ____/   / __ \\  / ___/  _____  ____ _   ____   / ____/  ____ \n  / /    / /       / /_/ /  \\__ \\  / ___/ / __ `/  / __ \\ / / __   / __ \\\n / /    / /___    / ____/  ___/ / / /__  / /_/ /  / / / // /_/ /  / /_/ /\n/_/     \\____/   /_/      /____/  \\___/  \\__,_/  /_/ /_/ \\____/   \\____/\n")
 	var (
-		usr_inputIP      string         //CIDR IP range from user input
-		usr_domain_input string         //Domain Names from user input
-		usr_domain_file  string         //Domain Names from file
-		usr_port_scan    string         //Ports to be scanned
-		thread_count     int            //Amount of routines to be used
-		usr_timeout      int            //Timeout duration
-		wg               sync.WaitGroup //Syncgroup for goroutines
+		usr_inputIP       string //IP addresses and ranges from user input
+		usr_domain_input  string //Domain Names from user input
+		usr_domain_file   string //Targets from file
+		usr_exclude       string //Targets not to be scanned
+		usr_exclude_file  string //Targets not to be scanned from file
+		usr_ipv6_limit    int    //Addresses scanned per IPv6 range
+		usr_dns_server    string //DNS server queried instead of the system resolver
+		usr_dns_type      string //Record types looked up
+		usr_dns_workers   int    //Domain names resolved at once
+		usr_vhosts        string //Virtual hosts requested on every web port
+		usr_vhost_file    string //Virtual hosts from file
+		usr_tech_rules    string //Technology fingerprinting rules file
+		usr_banner_limit  int    //Bytes read from a banner or probe response
+		usr_port_scan     string //Ports to be scanned
+		thread_count      int    //Amount of routines to be used
+		usr_timeout       int    //Timeout duration
+		usr_output        string //Output format
+		usr_output_file   string //Output file
+		usr_output_xml    string //File nmap compatible XML is written to
+		usr_output_html   string //File the HTML report is written to
+		usr_show_closed   bool   //Include closed and filtered ports
+		usr_resume        string //State file of an interrupted scan to continue
+		usr_rate          int    //Connections per second
+		usr_host_threads  int    //Targets per host scanned at once
+		usr_delay         int    //Milliseconds to wait before each connection
+		usr_jitter        int    //Random extra milliseconds added to the delay
+		usr_adaptive      bool   //Back off when timeouts spike
+		usr_modules       string //Enabled modules
+		usr_udp           bool   //Scan UDP ports
+		usr_syn           bool   //Half-open SYN scan
+		usr_no_discovery  bool   //Scan every host without pinging it first
+		usr_top_ports     int    //Amount of most common ports to scan
+		usr_exclude_ports string //Ports not to be scanned
+		usr_config        string //Configuration file
+		usr_profile       string //Scan profile
+		usr_save_state    string //File the state of an interrupted scan is written to
 	)
 
-	flag.StringVar(&usr_domain_input, "d", "", "Domain Name")
-	flag.StringVar(&usr_inputIP, "ip", "", "CIDR IP range")
-	flag.StringVar(&usr_domain_file, "df", "", "Domains to be scanned from file")
+	flag.StringVar(&usr_domain_input, "d", "", "Domain names, comma separated")
+	flag.StringVar(&usr_inputIP, "ip", "", "IP addresses, CIDR ranges or dash ranges (10.0.0.5-40), comma separated")
+	flag.StringVar(&usr_domain_file, "df", "", "Targets to be scanned from file, - for stdin")
+	flag.StringVar(&usr_exclude, "exclude", "", "Targets not to be scanned, comma separated")
+	flag.StringVar(&usr_exclude_file, "exclude-file", "", "Targets not to be scanned from file")
 	flag.IntVar(&thread_count, "t", 10, "Thread Count")
-	flag.StringVar(&usr_port_scan, "p", "1-1024", "Port Scan Range")
+	flag.IntVar(&usr_ipv6_limit, "ipv6-limit", 65536, "Addresses scanned from each IPv6 range, larger ranges are cut down")
+	flag.StringVar(&usr_dns_server, "dns-server", "", "DNS server to query (ip or ip:port), default system resolver")
+	flag.StringVar(&usr_dns_type, "dns-type", "A,AAAA", "Record types to look up (A, AAAA or both)")
+	flag.IntVar(&usr_dns_workers, "dns-workers", 50, "Domain names resolved at once")
+	flag.StringVar(&usr_vhosts, "vhosts", "", "Virtual hosts to request on every HTTP and TLS port, comma separated")
+	flag.StringVar(&usr_vhost_file, "vhost-file", "", "Virtual hosts to request from file")
+	flag.IntVar(&usr_banner_limit, "banner-limit", 4096, "Bytes read from a banner or probe response")
+	flag.StringVar(&usr_port_scan, "p", "1-1024", "Ports to scan: numbers, ranges, service names or sets ("+strings.Join(portSetNames(), ", ")+")")
+	flag.IntVar(&usr_top_ports, "top-ports", 0, "Scan the N most common ports")
+	flag.StringVar(&usr_exclude_ports, "exclude-ports", "", "Ports not to be scanned, same syntax as -p")
 	flag.IntVar(&usr_timeout, "time", 1, "Seconds of Timeout")
+	flag.StringVar(&usr_output, "o", "text", "Output format ("+strings.Join(output.Formats, ", ")+")")
+	flag.StringVar(&usr_output_file, "of", "", "Output file (default stdout, report.html for html)")
+	flag.StringVar(&usr_output_xml, "oX", "", "Also write nmap compatible XML to this file")
+	flag.StringVar(&usr_output_html, "oH", "", "Also write the HTML report to this file")
+	flag.BoolVar(&usr_show_closed, "show-closed", false, "Include closed and filtered ports in output")
+	flag.IntVar(&usr_rate, "rate", 0, "Maximum connections per second (0 for unlimited)")
+	flag.IntVar(&usr_host_threads, "host-threads", 0, "Maximum targets per host scanned at once (0 for unlimited)")
+	flag.IntVar(&usr_delay, "delay", 0, "Milliseconds each thread waits before a connection")
+	flag.IntVar(&usr_jitter, "jitter", 0, "Random extra milliseconds added to the delay")
+	flag.BoolVar(&usr_adaptive, "adaptive", false, "Slow down automatically when connections start timing out")
+	flag.BoolVar(&usr_udp, "sU", false, "UDP scan with protocol specific payloads")
+	flag.BoolVar(&usr_syn, "sS", false, "Half-open SYN scan, needs root or CAP_NET_RAW")
+	flag.BoolVar(&usr_no_discovery, "Pn", false, "Skip host discovery and scan every host")
+	flag.StringVar(&usr_modules, "modules", "all", "Enabled modules (banner, tls, http, probes, tech, favicon, os or all)")
+	flag.StringVar(&usr_tech_rules, "tech-rules", "", "Technology fingerprinting rules file (default bundled rules)")
+	flag.StringVar(&usr_config, "config", "", "YAML or TOML configuration file, command line flags take precedence")
+	flag.StringVar(&usr_profile, "profile", "", "Scan profile (quick, web, full or one defined in the configuration file)")
+	flag.StringVar(&usr_resume, "resume", "", "Continue the interrupted scan saved in this state file")
+	flag.StringVar(&usr_save_state, "save-state", "", "File to save the state to when interrupted (default resume.json or the -resume file)")
 	flag.Parse()
-
-	if err := utils.ValidateFlags(usr_domain_input, usr_inputIP, usr_domain_file); err != nil { //Some flags cannot be used together
+	report_file, err := reportFileArg(usr_output)
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		flag.Usage()
 		return
 	}
 
-	if thread_count < 0 || thread_count > 300 { //Limit threads
-		fmt.Println("Thread count violation!")
-		return
-	}
-	if usr_timeout < 0 { //Validate timeout
-		fmt.Println("Invalid timeout set!")
-		return
-	}
-	//Parse user provied port range
-	var port_input []int
-
-	portSpecs := strings.Split(usr_port_scan, ",")
-	for _, spec := range portSpecs {
-		if strings.Contains(spec, "-") {
-			rangePorts := strings.Split(spec, "-")
-			if len(rangePorts) != 2 {
-				fmt.Printf("Error: Invalid format! ==> %s", spec)
-				return
-			}
-			start_port, err1 := strconv.Atoi(rangePorts[0])
-			end_port, err2 := strconv.Atoi(rangePorts[1])
-
-			if err1 != nil || err2 != nil {
-				fmt.Printf("Error: Invalid port type! ==> %s", spec)
+	explicit_flags := make(map[string]bool) //Flags given on the command line
+	flag.Visit(func(f *flag.Flag) {
+		explicit_flags[f.Name] = true
+	})
+	var config_settings config.Settings
+	if usr_config != "" || usr_profile != "" { //Layer profile and configuration file under the command line
+		var config_file config.File
+		var err error
+		if usr_config != "" {
+			if config_file, err = config.Load(usr_config); err != nil {
+				fmt.Println("Error reading configuration file:", err)
 				return
 			}
-			if start_port <= 0 || end_port > 65535 || start_port > end_port {
-				fmt.Printf("Error: Invalid Range! ==> %s", spec)
-				return
-			}
-
-			for i := start_port; i <= end_port; i++ {
-				if !slices.Contains(port_input, i) {
-					port_input = append(port_input, i)
-				}
-			}
-		} else {
-			port, err := strconv.Atoi(spec)
-			if err != nil {
-				fmt.Println("Error: Invalid type!")
-				return
-			}
-			if port <= 0 || port > 65535 {
-				fmt.Printf("Error: Port out of range! ==> %s", spec)
-				return
-			}
-			if !slices.Contains(port_input, port) {
-				port_input = append(port_input, port)
-			}
+		}
+		if config_settings, err = config.Resolve(config_file, usr_profile); err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		if explicit_flags["d"] || explicit_flags["ip"] || explicit_flags["df"] || flag.NArg() > 0 { //Command line targets replace the configured ones
+			config_settings.Targets = nil
+			config_settings.DomainFile = ""
+		}
+		if err := config_settings.Apply(flag.CommandLine); err != nil {
+			fmt.Println("Error:", err)
+			return
 		}
 	}
 
-	//Execute Scan
-	var (
-		IP_addresses []string //Target IP addresses
-		err_parse    error    //Error from parsing IP addresses
-	)
-
-	if usr_inputIP != "" { //Perform CIDR IP scan
-		IP_addresses, err_parse = utils.CIDRRange(usr_inputIP)
-		if err_parse != nil {
-			fmt.Println(err_parse)
+	var resume_state scanner.State
+	if usr_resume != "" { //Targets and ports come from the state file
+		var err error
+		if resume_state, err = scanner.LoadState(usr_resume); err != nil {
+			fmt.Println("Error reading state file:", err)
 			return
 		}
-	} else if usr_domain_input != "" { //Perform Domain Name scan
-		IP_addresses, err_parse = net.LookupHost(usr_domain_input)
-		if err_parse != nil {
-			fmt.Println("No such domain found!")
+	}
+	//Gather targets from every source, they are deduplicated by the scanner
+	target_specs, err := targetSpecs(flag.Args(), usr_inputIP, usr_domain_input, usr_domain_file)
+	if err != nil {
+		fmt.Println("Error reading targets:", err)
+		return
+	}
+	target_specs = append(target_specs, config_settings.Targets...)
+	exclude_specs, err := targetSpecs(nil, usr_exclude, "", usr_exclude_file)
+	if err != nil {
+		fmt.Println("Error reading excluded targets:", err)
+		return
+	}
+	vhosts := targets.Split(usr_vhosts)
+	if usr_vhost_file != "" {
+		file_vhosts, err := targets.ReadFile(usr_vhost_file)
+		if err != nil {
+			fmt.Println("Error reading virtual hosts:", err)
 			return
 		}
-	} else if usr_domain_file != "" { //Perform Domain Name scan from file
-		file, err_parse := os.Open(usr_domain_file)
-		if err_parse != nil {
-			fmt.Println("Error opening file:", err_parse)
+		vhosts = append(vhosts, file_vhosts...)
+	}
+	for _, vhost := range vhosts {
+		if !targets.IsHostname(vhost) {
+			fmt.Printf("Error: invalid virtual host %q\n", vhost)
 			return
 		}
-
-		scanner := bufio.NewScanner(file)
-		for scanner.Scan() {
-			domain := scanner.Text()
-			if ip_address, err := net.LookupHost(domain); err == nil {
-				for i := 0; i < len(ip_address); i++ {
-					fmt.Println(ip_address[i])
-					IP_addresses = append(IP_addresses, ip_address[i])
-				}
-			} else {
-				fmt.Printf("No such domain found! ==> %s\n", domain)
-				continue
-			}
+	}
+	if usr_resume == "" && len(target_specs) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no input is given")
+		flag.Usage()
+		return
+	}
+	if usr_save_state == "" {
+		usr_save_state = "resume.json"
+		if usr_resume != "" {
+			usr_save_state = usr_resume
 		}
-		if err := scanner.Err(); err != nil {
-			fmt.Println("Error reading file:", err)
-			file.Close()
+	}
+
+	if thread_count < 0 || thread_count > 300 { //Limit threads
+		fmt.Println("Thread count violation!")
+		return
+	}
+	if usr_timeout < 0 { //Validate timeout
+		fmt.Println("Invalid timeout set!")
+		return
+	}
+	if usr_rate < 0 || usr_host_threads < 0 || usr_delay < 0 || usr_jitter < 0 { //Validate politeness controls
+		fmt.Println("Rate, host threads, delay and jitter cannot be negative!")
+		return
+	}
+	scan_modules, err := config.ParseModules(usr_modules)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flag.Usage()
+		return
+	}
+	if report_file != "" {
+		usr_output_file = report_file
+	}
+	output_sinks := []config.Output{{Format: usr_output, File: usr_output_file}}
+	if !explicit_flags["o"] && !explicit_flags["of"] && report_file == "" && len(config_settings.Outputs) > 0 {
+		output_sinks = config_settings.Outputs
+	}
+	if usr_output_xml != "" {
+		output_sinks = append(output_sinks, config.Output{Format: "xml", File: usr_output_xml})
+	}
+	if usr_output_html != "" {
+		output_sinks = append(output_sinks, config.Output{Format: "html", File: usr_output_html})
+	}
+	result_writer, close_outputs, err := openOutputs(output_sinks)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		flag.Usage()
+		return
+	}
+	defer close_outputs()
+	//Parse user provided ports
+	port_input, err := ports.Parse(usr_port_scan)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if usr_top_ports > 0 {
+		top, err := ports.Top(usr_top_ports)
+		if err != nil {
+			fmt.Println("Error:", err)
 			return
 		}
-		file.Close()
-	}
-	//Create targets
-	var targets []string
-	for i := 0; i < len(IP_addresses); i++ {
-		for j := 0; j < len(port_input); j++ {
-			target := fmt.Sprintf("%s:%d", IP_addresses[i], port_input[j])
-			targets = append(targets, target)
+		port_set := false //Ports given explicitly or by the configuration are kept
+		flag.Visit(func(f *flag.Flag) {
+			port_set = port_set || f.Name == "p"
+		})
+		if port_set {
+			port_input = append(port_input, ports.Exclude(top, port_input)...)
+		} else {
+			port_input = top
 		}
 	}
-	var (
-		port_range_dist        []int = utils.PortRangeDistribute(len(IP_addresses), port_input, thread_count) //Amount of targets per routine
-		comm_result_channel          = make(chan []scanner.TargetResult)                                      //Channel to communicate results of each routine
-		comm_up_result_channel       = make(chan bool)                                                        //Channel to communicate status of each routine
-		up_counter                   = len(port_range_dist)                                                   //Counter for routines that are up
+	if usr_exclude_ports != "" {
+		excluded, err := ports.Parse(usr_exclude_ports)
+		if err != nil {
+			fmt.Println("Error:", err)
+			return
+		}
+		port_input = ports.Exclude(port_input, excluded)
+	}
+	if len(port_input) == 0 {
+		fmt.Println("Error: every port is excluded")
+		return
+	}
+
+	//Execute Scan
+	summary := output.Summary{StartTime: time.Now(), Flags: map[string]string{}, Ports: port_input}
+	flag.Visit(func(f *flag.Flag) {
+		summary.Flags[f.Name] = f.Value.String()
+	})
+	lookup_ipv4, lookup_ipv6, err := parseRecordTypes(usr_dns_type)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	dns_resolver, err := resolver.New(
+		resolver.WithServer(usr_dns_server),
+		resolver.WithRecordTypes(lookup_ipv4, lookup_ipv6),
+		resolver.WithWorkers(usr_dns_workers),
 	)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	scan_targets, answers, err := resolveTargets(dns_resolver, target_specs)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	summary.Resolved = make(map[string][]string)
+	for _, answer := range answers {
+		if answer.Err == nil {
+			summary.Resolved[answer.Name] = answer.Addresses
+		}
+	}
+	scan_exclude, _, err := resolveTargets(dns_resolver, exclude_specs)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	summary.Targets = target_specs
+	scan_options := []scanner.Option{
+		scanner.WithTargets(scan_targets...),
+		scanner.WithExclude(scan_exclude...),
+		scanner.WithIPv6Limit(usr_ipv6_limit),
+		scanner.WithHostnames(resolver.Hosts(answers)),
+		scanner.WithVHosts(vhosts...),
+		scanner.WithTechRules(usr_tech_rules),
+		scanner.WithBannerLimit(usr_banner_limit),
+		scanner.WithPorts(port_input...),
+		scanner.WithConcurrency(thread_count),
+		scanner.WithTimeout(time.Duration(usr_timeout) * time.Second),
+		scanner.WithClosed(usr_show_closed),
+		scanner.WithModules(scan_modules),
+		scanner.WithUDP(usr_udp),
+		scanner.WithSYN(usr_syn),
+		scanner.WithRate(usr_rate),
+		scanner.WithHostConcurrency(usr_host_threads),
+		scanner.WithDelay(time.Duration(usr_delay)*time.Millisecond, time.Duration(usr_jitter)*time.Millisecond),
+		scanner.WithAdaptiveBackoff(usr_adaptive),
+		scanner.WithDiscovery(!usr_no_discovery),
+	}
+	if usr_resume != "" {
+		scan_options = append(scan_options, scanner.WithResume(resume_state))
+		summary.Targets = append(resume_state.CIDRs, resume_state.Targets...)
+		summary.Ports = resume_state.Ports
+	}
+	port_scanner, err := scanner.New(scan_options...)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+
+	for _, target := range port_scanner.Truncated() {
+		fmt.Fprintf(os.Stderr, "Warning: %s is too large, scanning its first %d addresses only\n", target, usr_ipv6_limit)
+	}
+	for _, pattern := range port_scanner.SkippedTechRules() {
+		fmt.Fprintf(os.Stderr, "Warning: skipping technology pattern %s\n", pattern)
+	}
 
-	port_index := 0
-	for i := 0; i < len(port_range_dist); i++ { //Start routines
-		wg.Add(1)
-		go scanner.ScanPort(comm_up_result_channel, comm_result_channel, targets[port_index:port_index+port_range_dist[i]], time.Duration(usr_timeout)*time.Second, &wg)
-		port_index += port_range_dist[i]
-	}
-	for i := 0; i < len(port_range_dist); i++ { //Recieve status of each routine
-		val_bool := <-comm_up_result_channel
-		if !val_bool {
-			up_counter--
+	//Stop dispatching on SIGINT/SIGTERM, a second signal kills the process
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	results, err := port_scanner.Run(ctx)
+	if err != nil {
+		fmt.Println("Error:", err)
+		return
+	}
+	if err := port_scanner.SYNError(); err != nil {
+		fmt.Fprintln(os.Stderr, "SYN scan unavailable, falling back to connect scan:", err)
+	}
+	tech_print := color.New(color.FgRed, color.Bold)
+	for result := range results {
+		if len(result.Technologies) > 0 {
+			tech_print.Fprintf(os.Stderr, "%s: %s\n", net.JoinHostPort(result.HostIP, strconv.Itoa(result.Port)), report.FormatTechnologies(result.Technologies))
+		}
+		if err := result_writer.WriteResult(result); err != nil {
+			fmt.Fprintln(os.Stderr, "Error writing result:", err)
 		}
 	}
-	wg.Wait()
-	for i := 0; i < up_counter; i++ { //Recieve results from each routine that is up
-		ret_val := <-comm_result_channel
-		fmt.Println(ret_val)
+	summary.EndTime = time.Now()
+	summary.Interrupted = ctx.Err() != nil
+	summary.HostsUp, summary.HostsDown = port_scanner.HostsDiscovered()
+	summary.OperatingSystems = port_scanner.OperatingSystems()
+	if err := result_writer.Close(summary); err != nil {
+		fmt.Fprintln(os.Stderr, "Error writing output:", err)
+	}
+	if summary.Interrupted { //Save what is left for -resume
+		if err := port_scanner.State().Save(usr_save_state); err != nil {
+			fmt.Fprintln(os.Stderr, "Error saving state:", err)
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Scan interrupted, continue with -resume %s\n", usr_save_state)
 	}
 }
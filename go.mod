@@ -2,7 +2,11 @@ module github.com/efecankaya/go-port-scanner
 
 go 1.21.3
 
-require github.com/valyala/fasthttp v1.52.0
+require (
+	github.com/BurntSushi/toml v1.6.0
+	github.com/valyala/fasthttp v1.52.0
+	gopkg.in/yaml.v3 v3.0.1
+)
 
 require (
 	github.com/andybalholm/brotli v1.1.0 // indirect
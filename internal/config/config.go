@@ -0,0 +1,322 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"github.com/efecankaya/go-port-scanner/scanner"
+	"gopkg.in/yaml.v3"
+)
+
+// Settings mirrors the command line options. Zero values and nil switches are
+// treated as not set so profiles and files can be layered on top of each
+// other, a switch set to false turns off what a lower layer turned on.
+type Settings struct {
+	Targets      []string `yaml:"targets" toml:"targets"`             //IPs, CIDR ranges or domain names
+	DomainFile   string   `yaml:"domain_file" toml:"domain_file"`     //File of targets
+	Exclude      []string `yaml:"exclude" toml:"exclude"`             //Targets not to be scanned
+	ExcludeFile  string   `yaml:"exclude_file" toml:"exclude_file"`   //File of targets not to be scanned
+	IPv6Limit    int      `yaml:"ipv6_limit" toml:"ipv6_limit"`       //Addresses scanned per IPv6 range
+	DNSServer    string   `yaml:"dns_server" toml:"dns_server"`       //DNS server queried instead of the system resolver
+	DNSType      string   `yaml:"dns_type" toml:"dns_type"`           //Record types looked up
+	VHosts       []string `yaml:"vhosts" toml:"vhosts"`               //Virtual hosts requested on every web port
+	TechRules    string   `yaml:"tech_rules" toml:"tech_rules"`       //Technology fingerprinting rules file
+	BannerLimit  int      `yaml:"banner_limit" toml:"banner_limit"`   //Bytes read from a banner or probe response
+	Ports        string   `yaml:"ports" toml:"ports"`                 //Port specification, same syntax as -p
+	TopPorts     int      `yaml:"top_ports" toml:"top_ports"`         //Amount of most common ports to scan
+	ExcludePorts string   `yaml:"exclude_ports" toml:"exclude_ports"` //Ports not to be scanned
+	Threads      int      `yaml:"threads" toml:"threads"`             //Amount of routines to be used
+	Timeout      int      `yaml:"timeout" toml:"timeout"`             //Seconds of timeout
+	Rate         int      `yaml:"rate" toml:"rate"`                   //Connections per second
+	HostThreads  int      `yaml:"host_threads" toml:"host_threads"`   //Targets per host scanned at once
+	Delay        int      `yaml:"delay" toml:"delay"`                 //Milliseconds to wait before each connection
+	Jitter       int      `yaml:"jitter" toml:"jitter"`               //Random extra milliseconds added to the delay
+	Adaptive     *bool    `yaml:"adaptive" toml:"adaptive"`           //Back off when timeouts spike
+	ShowClosed   *bool    `yaml:"show_closed" toml:"show_closed"`     //Include closed and filtered ports
+	NoDiscovery  *bool    `yaml:"no_discovery" toml:"no_discovery"`   //Scan every host without pinging it first
+	UDP          *bool    `yaml:"udp" toml:"udp"`                     //Scan UDP ports instead of TCP
+	SYN          *bool    `yaml:"syn" toml:"syn"`                     //Half-open SYN scan
+	Modules      []string `yaml:"modules" toml:"modules"`             //Enabled modules, see ParseModules
+	Outputs      []Output `yaml:"outputs" toml:"outputs"`             //Output sinks
+	XMLFile      string   `yaml:"xml_file" toml:"xml_file"`           //File nmap compatible XML is also written to
+	HTMLFile     string   `yaml:"html_file" toml:"html_file"`         //File the HTML report is also written to
+}
+
+type Output struct {
+	Format string `yaml:"format" toml:"format"` //Output format
+	File   string `yaml:"file" toml:"file"`     //Output file, stdout if empty
+}
+
+// File is the layout of a configuration file. Top level settings apply on top
+// of the selected profile.
+type File struct {
+	Profile  string              `yaml:"profile" toml:"profile"` //Profile to start from
+	Settings `yaml:",inline"`    //Settings of the scan
+	Profiles map[string]Settings `yaml:"profiles" toml:"profiles"` //Profiles defined by the file
+}
+
+// Profiles are the built-in scan definitions, files may add or replace them.
+var Profiles = map[string]Settings{
+	"quick": {
+		TopPorts: 100,
+		Threads:  100,
+		Timeout:  1,
+		Modules:  []string{"banner", "tls", "http"},
+	},
+	"web": {
+		Ports:   "web",
+		Threads: 50,
+		Timeout: 3,
+		Modules: []string{"tls", "http", "tech", "favicon"},
+	},
+	"full": {
+		Ports:   "1-65535",
+		Threads: 300,
+		Timeout: 2,
+		Modules: []string{"all"},
+	},
+}
+
+// Load reads a YAML or, for the .toml extension, TOML configuration file.
+func Load(path string) (File, error) {
+	var file File
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		_, err := toml.DecodeFile(path, &file)
+		return file, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, err
+	}
+	err = yaml.Unmarshal(data, &file)
+	return file, err
+}
+
+// Resolve layers the settings of file over the named profile. An empty
+// profile falls back to the one selected by the file.
+func Resolve(file File, profile string) (Settings, error) {
+	if profile == "" {
+		profile = file.Profile
+	}
+	var settings Settings
+	if profile != "" {
+		base, ok := file.Profiles[profile]
+		if !ok {
+			base, ok = Profiles[profile]
+		}
+		if !ok {
+			return settings, fmt.Errorf("unknown profile %q", profile)
+		}
+		settings = base
+	}
+	return settings.Merge(file.Settings), nil
+}
+
+// Merge returns s with every field set in other replaced.
+func (s Settings) Merge(other Settings) Settings {
+	if len(other.Targets) > 0 {
+		s.Targets = other.Targets
+	}
+	if other.DomainFile != "" {
+		s.DomainFile = other.DomainFile
+	}
+	if len(other.Exclude) > 0 {
+		s.Exclude = other.Exclude
+	}
+	if other.ExcludeFile != "" {
+		s.ExcludeFile = other.ExcludeFile
+	}
+	if other.IPv6Limit != 0 {
+		s.IPv6Limit = other.IPv6Limit
+	}
+	if other.DNSServer != "" {
+		s.DNSServer = other.DNSServer
+	}
+	if other.BannerLimit != 0 {
+		s.BannerLimit = other.BannerLimit
+	}
+	if other.DNSType != "" {
+		s.DNSType = other.DNSType
+	}
+	if len(other.VHosts) > 0 {
+		s.VHosts = other.VHosts
+	}
+	if other.TechRules != "" {
+		s.TechRules = other.TechRules
+	}
+	if other.Ports != "" {
+		s.Ports = other.Ports
+	}
+	if other.TopPorts != 0 {
+		s.TopPorts = other.TopPorts
+	}
+	if other.ExcludePorts != "" {
+		s.ExcludePorts = other.ExcludePorts
+	}
+	if other.Threads != 0 {
+		s.Threads = other.Threads
+	}
+	if other.Timeout != 0 {
+		s.Timeout = other.Timeout
+	}
+	if other.Rate != 0 {
+		s.Rate = other.Rate
+	}
+	if other.HostThreads != 0 {
+		s.HostThreads = other.HostThreads
+	}
+	if other.Delay != 0 {
+		s.Delay = other.Delay
+	}
+	if other.Jitter != 0 {
+		s.Jitter = other.Jitter
+	}
+	if other.Adaptive != nil {
+		s.Adaptive = other.Adaptive
+	}
+	if other.ShowClosed != nil {
+		s.ShowClosed = other.ShowClosed
+	}
+	if other.NoDiscovery != nil {
+		s.NoDiscovery = other.NoDiscovery
+	}
+	if other.UDP != nil {
+		s.UDP = other.UDP
+	}
+	if other.SYN != nil {
+		s.SYN = other.SYN
+	}
+	if len(other.Modules) > 0 {
+		s.Modules = other.Modules
+	}
+	if len(other.Outputs) > 0 {
+		s.Outputs = other.Outputs
+	}
+	if other.XMLFile != "" {
+		s.XMLFile = other.XMLFile
+	}
+	if other.HTMLFile != "" {
+		s.HTMLFile = other.HTMLFile
+	}
+	return s
+}
+
+// FlagValues maps the set fields to the command line flags they correspond
+// to. Targets and outputs have no single flag and are left out.
+func (s Settings) FlagValues() map[string]string {
+	values := make(map[string]string)
+	setInt := func(name string, value int) {
+		if value != 0 {
+			values[name] = strconv.Itoa(value)
+		}
+	}
+	if s.DomainFile != "" {
+		values["df"] = s.DomainFile
+	}
+	if len(s.Exclude) > 0 {
+		values["exclude"] = strings.Join(s.Exclude, ",")
+	}
+	if s.ExcludeFile != "" {
+		values["exclude-file"] = s.ExcludeFile
+	}
+	if s.DNSServer != "" {
+		values["dns-server"] = s.DNSServer
+	}
+	if s.DNSType != "" {
+		values["dns-type"] = s.DNSType
+	}
+	if len(s.VHosts) > 0 {
+		values["vhosts"] = strings.Join(s.VHosts, ",")
+	}
+	if s.TechRules != "" {
+		values["tech-rules"] = s.TechRules
+	}
+	if s.Ports != "" {
+		values["p"] = s.Ports
+	}
+	if s.ExcludePorts != "" {
+		values["exclude-ports"] = s.ExcludePorts
+	}
+	setInt("ipv6-limit", s.IPv6Limit)
+	setInt("banner-limit", s.BannerLimit)
+	setInt("top-ports", s.TopPorts)
+	setInt("t", s.Threads)
+	setInt("time", s.Timeout)
+	setInt("rate", s.Rate)
+	setInt("host-threads", s.HostThreads)
+	setInt("delay", s.Delay)
+	setInt("jitter", s.Jitter)
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			values[name] = strconv.FormatBool(*value)
+		}
+	}
+	setBool("adaptive", s.Adaptive)
+	setBool("show-closed", s.ShowClosed)
+	setBool("Pn", s.NoDiscovery)
+	setBool("sU", s.UDP)
+	setBool("sS", s.SYN)
+	if len(s.Modules) > 0 {
+		values["modules"] = strings.Join(s.Modules, ",")
+	}
+	if s.XMLFile != "" {
+		values["oX"] = s.XMLFile
+	}
+	if s.HTMLFile != "" {
+		values["oH"] = s.HTMLFile
+	}
+	return values
+}
+
+// Apply sets the flags of set that were not given on the command line to
+// the values of s, so explicit flags take precedence over the configuration.
+func (s Settings) Apply(set *flag.FlagSet) error {
+	explicit := make(map[string]bool)
+	set.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+	for name, value := range s.FlagValues() {
+		if explicit[name] {
+			continue
+		}
+		if err := set.Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s in configuration: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// ParseModules parses a comma separated module list. Known modules are
+// banner, tls, http, probes, tech, favicon and os; all enables every one of them.
+func ParseModules(list string) (scanner.Modules, error) {
+	var modules scanner.Modules
+	for _, name := range strings.Split(list, ",") {
+		switch strings.TrimSpace(strings.ToLower(name)) {
+		case "all":
+			modules = scanner.AllModules
+		case "banner":
+			modules.Banner = true
+		case "tls":
+			modules.TLS = true
+		case "http":
+			modules.HTTP = true
+		case "probes":
+			modules.ServiceProbes = true
+		case "tech":
+			modules.TechFinder = true
+		case "favicon":
+			modules.Favicon = true
+		case "os":
+			modules.OS = true
+		case "":
+		default:
+			return modules, fmt.Errorf("unknown module %q", name)
+		}
+	}
+	return modules, nil
+}
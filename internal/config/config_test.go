@@ -0,0 +1,227 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+const testYAML = `
+profile: deep
+threads: 20
+show_closed: false
+xml_file: scan.xml
+outputs:
+  - format: json
+    file: scan.json
+profiles:
+  deep:
+    ports: 1-65535
+    timeout: 5
+    show_closed: true
+    adaptive: true
+    syn: true
+`
+
+const testTOML = `
+profile = "deep"
+threads = 20
+show_closed = false
+xml_file = "scan.xml"
+
+[[outputs]]
+format = "json"
+file = "scan.json"
+
+[profiles.deep]
+ports = "1-65535"
+timeout = 5
+show_closed = true
+adaptive = true
+syn = true
+`
+
+func TestLoad(t *testing.T) {
+	for name, content := range map[string]string{"scan.yaml": testYAML, "scan.toml": testTOML} {
+		file, err := Load(writeFile(t, name, content))
+		if err != nil {
+			t.Fatalf("Load(%s) error: %v", name, err)
+		}
+		if file.Profile != "deep" || file.Threads != 20 || file.XMLFile != "scan.xml" {
+			t.Errorf("%s: top level settings = %+v", name, file.Settings)
+		}
+		if file.ShowClosed == nil || *file.ShowClosed {
+			t.Errorf("%s: show_closed = %v, want set to false", name, file.ShowClosed)
+		}
+		if file.Adaptive != nil {
+			t.Errorf("%s: adaptive set at the top level", name)
+		}
+		if want := []Output{{Format: "json", File: "scan.json"}}; !reflect.DeepEqual(file.Outputs, want) {
+			t.Errorf("%s: outputs = %+v, want %+v", name, file.Outputs, want)
+		}
+		deep := file.Profiles["deep"]
+		if deep.Ports != "1-65535" || deep.Timeout != 5 || deep.SYN == nil || !*deep.SYN {
+			t.Errorf("%s: profile = %+v", name, deep)
+		}
+	}
+	if _, err := Load(writeFile(t, "bad.yaml", "threads: [")); err == nil {
+		t.Error("Load of malformed YAML succeeded")
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Load of a missing file succeeded")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	file, err := Load(writeFile(t, "scan.yaml", testYAML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := Resolve(file, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Ports != "1-65535" || settings.Timeout != 5 || settings.Threads != 20 {
+		t.Errorf("settings = %+v, want the profile with the file's threads", settings)
+	}
+	if *settings.ShowClosed || !*settings.Adaptive || !*settings.SYN || settings.UDP != nil {
+		t.Errorf("switches = show_closed %v adaptive %v syn %v udp %v, want the file to turn show_closed off",
+			*settings.ShowClosed, *settings.Adaptive, *settings.SYN, settings.UDP)
+	}
+
+	//Built-in profiles are found too, the file's settings still apply
+	settings, err = Resolve(file, "quick")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.TopPorts != 100 || settings.Threads != 20 || settings.Ports != "" {
+		t.Errorf("quick settings = %+v", settings)
+	}
+	if _, err := Resolve(file, "missing"); err == nil {
+		t.Error("Resolve of an unknown profile succeeded")
+	}
+	if settings, err := Resolve(File{Settings: Settings{Threads: 7}}, ""); err != nil || settings.Threads != 7 {
+		t.Errorf("Resolve without profile = %+v, %v", settings, err)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	base := Settings{Threads: 10, Ports: "web", ShowClosed: boolPtr(true), UDP: boolPtr(true), Modules: []string{"all"}}
+	merged := base.Merge(Settings{Threads: 30, UDP: boolPtr(false), HTMLFile: "report.html"})
+	if merged.Threads != 30 || merged.Ports != "web" || merged.HTMLFile != "report.html" {
+		t.Errorf("merged = %+v", merged)
+	}
+	if !*merged.ShowClosed || *merged.UDP {
+		t.Errorf("show_closed %v udp %v, want unset switches kept and set ones replaced", *merged.ShowClosed, *merged.UDP)
+	}
+	if !reflect.DeepEqual(merged.Modules, []string{"all"}) {
+		t.Errorf("modules = %q", merged.Modules)
+	}
+}
+
+func TestFlagValues(t *testing.T) {
+	settings := Settings{
+		Ports:       "22,80",
+		Threads:     50,
+		Exclude:     []string{"10.0.0.1", "10.0.0.2"},
+		ShowClosed:  boolPtr(false),
+		NoDiscovery: boolPtr(true),
+		SYN:         boolPtr(true),
+		Modules:     []string{"tls", "http"},
+		XMLFile:     "scan.xml",
+		HTMLFile:    "report.html",
+		Targets:     []string{"10.0.0.0/24"},
+		Outputs:     []Output{{Format: "json"}},
+	}
+	want := map[string]string{
+		"p":           "22,80",
+		"t":           "50",
+		"exclude":     "10.0.0.1,10.0.0.2",
+		"show-closed": "false",
+		"Pn":          "true",
+		"sS":          "true",
+		"modules":     "tls,http",
+		"oX":          "scan.xml",
+		"oH":          "report.html",
+	}
+	if got := settings.FlagValues(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FlagValues = %v, want %v", got, want)
+	}
+}
+
+func TestApply(t *testing.T) {
+	settings, err := Resolve(File{Profile: "quick", Settings: Settings{Threads: 20, ShowClosed: boolPtr(true), SYN: boolPtr(true)}}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	set := flag.NewFlagSet("port-scanner", flag.ContinueOnError)
+	threads := set.Int("t", 10, "")
+	timeout := set.Int("time", 1, "")
+	top_ports := set.Int("top-ports", 0, "")
+	show_closed := set.Bool("show-closed", false, "")
+	syn := set.Bool("sS", false, "")
+	modules := set.String("modules", "all", "")
+	for _, name := range []string{"p", "oX", "oH", "adaptive", "Pn", "sU"} {
+		set.String(name, "", "")
+	}
+	if err := set.Parse([]string{"-t", "5", "-show-closed=false"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := settings.Apply(set); err != nil {
+		t.Fatal(err)
+	}
+	//Explicit flags win, the rest comes from the profile and the file
+	if *threads != 5 || *show_closed || *timeout != 1 || *top_ports != 100 || !*syn || *modules != "banner,tls,http" {
+		t.Errorf("flags = t %d show-closed %v time %d top-ports %d sS %v modules %q",
+			*threads, *show_closed, *timeout, *top_ports, *syn, *modules)
+	}
+
+	bad := Settings{Threads: 4}
+	if err := bad.Apply(flag.NewFlagSet("empty", flag.ContinueOnError)); err == nil {
+		t.Error("Apply to a set without the flag succeeded")
+	}
+}
+
+func TestParseModules(t *testing.T) {
+	tests := []struct {
+		list string
+		want scanner.Modules
+		err  bool
+	}{
+		{list: "all", want: scanner.AllModules},
+		{list: "banner", want: scanner.Modules{Banner: true}},
+		{list: " TLS , http ,", want: scanner.Modules{TLS: true, HTTP: true}},
+		{list: "probes,tech,favicon,os", want: scanner.Modules{ServiceProbes: true, TechFinder: true, Favicon: true, OS: true}},
+		{list: "", want: scanner.Modules{}},
+		{list: "banner,ftp", err: true},
+	}
+	for _, test := range tests {
+		got, err := ParseModules(test.list)
+		if test.err {
+			if err == nil {
+				t.Errorf("ParseModules(%q) succeeded", test.list)
+			}
+			continue
+		}
+		if err != nil || got != test.want {
+			t.Errorf("ParseModules(%q) = %+v, %v, want %+v", test.list, got, err, test.want)
+		}
+	}
+}
@@ -1,19 +1,82 @@
+// Package banner reads what services say, either on their own right after the
+// connection is made or in answer to one of a library of protocol hellos.
+// Responses are kept as raw bytes and rendered printable for display.
 package banner
 
 import (
-	"bufio"
+	"errors"
+	"fmt"
 	"net"
 	"strings"
 	"time"
 )
 
-func GrabBanner(conn net.Conn, timeout time.Duration) (string, error) {
-	reader := bufio.NewReader(conn)
+// DefaultLimit is the number of bytes read from a response unless another
+// limit is given.
+const DefaultLimit = 4096
+
+var ErrNoResponse = errors.New("no response received")
+
+// Grab passively reads what the service on conn sends on its own. It waits up
+// to timeout for the first bytes and returns once the service stops talking,
+// closes the connection or limit bytes are read.
+func Grab(conn net.Conn, timeout time.Duration, limit int) ([]byte, error) {
 	conn.SetReadDeadline(time.Now().Add(timeout))
-	response, err := reader.ReadString('\n')
+	return read(conn, timeout, limit)
+}
+
+// SendProbe opens a new connection to target, writes payload (if any) and
+// reads the answer like Grab.
+func SendProbe(target string, payload []byte, timeout time.Duration, limit int) ([]byte, error) {
+	conn, err := net.DialTimeout("tcp", target, timeout)
 	if err != nil {
-		return "", err
-	} else {
-		return strings.TrimSpace(response), nil
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if len(payload) > 0 {
+		if _, err := conn.Write(payload); err != nil {
+			return nil, err
+		}
+	}
+	return read(conn, timeout, limit)
+}
+
+func read(conn net.Conn, timeout time.Duration, limit int) ([]byte, error) {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	response := make([]byte, 0, min(limit, DefaultLimit))
+	buf := make([]byte, min(limit, DefaultLimit))
+	for len(response) < limit {
+		n, err := conn.Read(buf[:min(len(buf), limit-len(response))])
+		response = append(response, buf[:n]...)
+		if err != nil {
+			break
+		}
+		//Once data arrived only wait shortly for the rest of it
+		conn.SetReadDeadline(time.Now().Add(timeout / 4))
+	}
+	if len(response) == 0 {
+		return nil, ErrNoResponse
+	}
+	return response, nil
+}
+
+// Printable renders raw bytes as text, escaping anything that is not
+// printable ASCII.
+func Printable(data []byte) string {
+	var sb strings.Builder
+	for _, b := range data {
+		switch {
+		case b == '\r' || b == '\n' || b == '\t':
+			sb.WriteByte(b)
+		case b >= 0x20 && b < 0x7f:
+			sb.WriteByte(b)
+		default:
+			fmt.Fprintf(&sb, "\\x%02x", b)
+		}
 	}
+	return strings.TrimSpace(sb.String())
 }
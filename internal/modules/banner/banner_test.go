@@ -0,0 +1,137 @@
+package banner
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serve accepts connections on loopback and hands each to handle.
+func serve(t *testing.T, handle func(net.Conn)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				handle(conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func TestGrab(t *testing.T) {
+	target := serve(t, func(conn net.Conn) {
+		conn.Write([]byte("SSH-2.0-OpenSSH_9.6\r\n"))
+		time.Sleep(time.Second) //Keep the connection open, Grab must not wait for the close
+	})
+	conn, err := net.Dial("tcp", target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	start := time.Now()
+	response, err := Grab(conn, 400*time.Millisecond, 0)
+	if err != nil || string(response) != "SSH-2.0-OpenSSH_9.6\r\n" {
+		t.Errorf("Grab = %q, %v", response, err)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("Grab took %v after the banner arrived, want about a quarter of the timeout", elapsed)
+	}
+
+	silent := serve(t, func(conn net.Conn) { time.Sleep(time.Second) })
+	if conn, err = net.Dial("tcp", silent); err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if _, err := Grab(conn, 50*time.Millisecond, 0); !errors.Is(err, ErrNoResponse) {
+		t.Errorf("Grab of a silent service error = %v, want %v", err, ErrNoResponse)
+	}
+}
+
+func TestSendProbe(t *testing.T) {
+	target := serve(t, func(conn net.Conn) {
+		request := make([]byte, 64)
+		n, _ := conn.Read(request)
+		conn.Write(bytes.Repeat(request[:n], 2000/max(n, 1)))
+		conn.Write(bytes.Repeat([]byte("x"), 8000))
+	})
+	response, err := SendProbe(target, []byte("PING\r\n"), time.Second, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(response) != DefaultLimit || !strings.HasPrefix(string(response), "PING\r\nPING\r\n") {
+		t.Errorf("SendProbe read %d bytes starting %q, want the echo up to %d bytes", len(response), response[:min(len(response), 12)], DefaultLimit)
+	}
+	for _, limit := range []int{1, 100, 4096, 6000} {
+		response, err := SendProbe(target, []byte("PING\r\n"), time.Second, limit)
+		if err != nil || len(response) != limit {
+			t.Errorf("SendProbe with limit %d read %d bytes, %v", limit, len(response), err)
+		}
+	}
+
+	closer := serve(t, func(conn net.Conn) { io.Copy(io.Discard, io.LimitReader(conn, 1)) })
+	if _, err := SendProbe(closer, []byte("HELP\r\n"), time.Second, 0); !errors.Is(err, ErrNoResponse) {
+		t.Errorf("SendProbe to a service closing silently error = %v, want %v", err, ErrNoResponse)
+	}
+	listener, _ := net.Listen("tcp4", "127.0.0.1:0")
+	closed := listener.Addr().String()
+	listener.Close()
+	if _, err := SendProbe(closed, nil, time.Second, 0); err == nil {
+		t.Error("SendProbe to a closed port succeeded")
+	}
+}
+
+func TestPrintable(t *testing.T) {
+	tests := map[string]string{
+		"SSH-2.0-OpenSSH_9.6\r\n":        "SSH-2.0-OpenSSH_9.6",
+		"220 ready\r\n\tsecond line\r\n": "220 ready\r\n\tsecond line",
+		"\x00\x01ab\xff":                 `\x00\x01ab\xff`,
+		"caf\xc3\xa9":                    `caf\xc3\xa9`,
+		"  \r\n":                         "",
+	}
+	for raw, want := range tests {
+		if got := Printable([]byte(raw)); got != want {
+			t.Errorf("Printable(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
+
+func TestProbesFor(t *testing.T) {
+	names := func(probes []Probe) []string {
+		var list []string
+		for _, probe := range probes {
+			list = append(list, probe.Name)
+		}
+		return list
+	}
+	generic := []string{"generic-lines", "get-request", "help"}
+	if got := names(ProbesFor(6379)); !slices.Equal(got, append([]string{"redis"}, generic...)) {
+		t.Errorf("ProbesFor(6379) = %q, want redis before the generic probes", got)
+	}
+	if got := names(ProbesFor(445)); got[0] != "smb" {
+		t.Errorf("ProbesFor(445) = %q, want smb first", got)
+	}
+	if got := names(ProbesFor(12345)); !slices.Equal(got, generic) {
+		t.Errorf("ProbesFor(12345) = %q, want the generic probes", got)
+	}
+	for _, probe := range Probes {
+		if probe.Name == "" {
+			t.Error("probe without a name, match rules cannot reference it")
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package banner
+
+import (
+	"encoding/binary"
+	"slices"
+)
+
+// Probe is a hello sent to make a service reveal itself.
+type Probe struct {
+	Name    string //Name of the probe, referenced by match rules
+	Payload []byte //Bytes sent after connecting, nil for a passive read
+	Ports   []int  //Ports the probe is sent to, empty for every port
+}
+
+// PassiveProbe names banners read without sending anything. It is empty so
+// the probe field is left out of JSON output.
+const PassiveProbe = ""
+
+// Probes lists the hellos in the order they are tried, port specific probes
+// are sent before the generic ones.
+var Probes = []Probe{
+	{Name: "mysql", Payload: nil, Ports: []int{3306, 3307}},
+	{Name: "redis", Payload: []byte("INFO server\r\n"), Ports: []int{6379, 6380, 16379}},
+	{Name: "memcached", Payload: []byte("version\r\n"), Ports: []int{11211}},
+	{Name: "postgresql", Payload: []byte{0x00, 0x00, 0x00, 0x08, 0x04, 0xd2, 0x16, 0x2f}, Ports: []int{5432, 5433}}, //SSLRequest
+	{Name: "mongodb", Payload: mongoIsMaster(), Ports: []int{27017, 27018, 27019}},
+	{Name: "rdp", Payload: []byte{0x03, 0x00, 0x00, 0x13, 0x0e, 0xe0, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x08, 0x00, 0x03, 0x00, 0x00, 0x00}, Ports: []int{3389}}, //X.224 connection request
+	{Name: "smb", Payload: smbNegotiate(), Ports: []int{139, 445}},
+	{Name: "rtsp", Payload: []byte("OPTIONS / RTSP/1.0\r\nCSeq: 1\r\n\r\n"), Ports: []int{554, 8554}},
+	{Name: "sip", Payload: []byte("OPTIONS sip:nm SIP/2.0\r\nVia: SIP/2.0/TCP nm;branch=foo\r\nFrom: <sip:nm@nm>;tag=root\r\nTo: <sip:nm2@nm2>\r\nCall-ID: 50000\r\nCSeq: 42 OPTIONS\r\nMax-Forwards: 70\r\nContent-Length: 0\r\n\r\n"), Ports: []int{5060, 5061}},
+	{Name: "generic-lines", Payload: []byte("\r\n\r\n")},
+	{Name: "get-request", Payload: []byte("GET / HTTP/1.0\r\n\r\n")},
+	{Name: "help", Payload: []byte("HELP\r\n")},
+}
+
+// ProbesFor returns the probes worth sending to port, port specific probes
+// first.
+func ProbesFor(port int) []Probe {
+	var specific, generic []Probe
+	for _, probe := range Probes {
+		if len(probe.Ports) == 0 {
+			generic = append(generic, probe)
+		} else if slices.Contains(probe.Ports, port) {
+			specific = append(specific, probe)
+		}
+	}
+	return append(specific, generic...)
+}
+
+// mongoIsMaster builds an OP_QUERY isMaster command against admin.$cmd.
+func mongoIsMaster() []byte {
+	query := []byte{0x13, 0x00, 0x00, 0x00, 0x10}
+	query = append(query, "isMaster\x00"...)
+	query = append(query, 0x01, 0x00, 0x00, 0x00, 0x00)
+
+	body := binary.LittleEndian.AppendUint32(nil, 0) //flags
+	body = append(body, "admin.$cmd\x00"...)
+	body = binary.LittleEndian.AppendUint32(body, 0) //numberToSkip
+	body = binary.LittleEndian.AppendUint32(body, 1) //numberToReturn
+	body = append(body, query...)
+
+	msg := binary.LittleEndian.AppendUint32(nil, uint32(16+len(body)))
+	msg = binary.LittleEndian.AppendUint32(msg, 1)    //requestID
+	msg = binary.LittleEndian.AppendUint32(msg, 0)    //responseTo
+	msg = binary.LittleEndian.AppendUint32(msg, 2004) //OP_QUERY
+	return append(msg, body...)
+}
+
+// smbNegotiate builds an SMB1 negotiate request offering SMB 2 as well, so both
+// old and current servers answer.
+func smbNegotiate() []byte {
+	var dialects []byte
+	for _, dialect := range []string{"NT LM 0.12", "SMB 2.002", "SMB 2.???"} {
+		dialects = append(append(append(dialects, 0x02), dialect...), 0x00)
+	}
+	header := []byte{
+		0xff, 'S', 'M', 'B', //Protocol
+		0x72,                   //Negotiate
+		0x00, 0x00, 0x00, 0x00, //Status
+		0x18,       //Flags: canonical paths, case insensitive
+		0x01, 0x48, //Flags2: long names, NT status, unicode
+		0x00, 0x00, //PID high
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, //Signature
+		0x00, 0x00, //Reserved
+		0xff, 0xff, //TID
+		0xfe, 0xff, //PID
+		0x00, 0x00, //UID
+		0x00, 0x00, //MID
+	}
+	body := append([]byte{0x00}, binary.LittleEndian.AppendUint16(nil, uint16(len(dialects)))...) //Word count, byte count
+	msg := append(append(header, body...), dialects...)
+	return append([]byte{0x00, 0x00, byte(len(msg) >> 8), byte(len(msg))}, msg...) //NetBIOS session message
+}
@@ -0,0 +1,94 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"strings"
+)
+
+const arpTable = "/proc/net/arp"
+
+// localNet is a directly connected IPv4 subnet and the interface it is on.
+type localNet struct {
+	ipnet *net.IPNet
+	iface net.Interface
+}
+
+// localNets lists the IPv4 subnets of the interfaces that are up and have a
+// hardware address, loopback excluded.
+func localNets() []localNet {
+	var nets []localNet
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) != 6 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil {
+				nets = append(nets, localNet{ipnet: ipnet, iface: iface})
+			}
+		}
+	}
+	return nets
+}
+
+// arpRequest builds an Ethernet ARP request asking who has target, sent from
+// the hardware address hw and address src.
+func arpRequest(hw net.HardwareAddr, src, target net.IP) []byte {
+	packet := make([]byte, 28)
+	binary.BigEndian.PutUint16(packet[0:], 1)      //Hardware type Ethernet
+	binary.BigEndian.PutUint16(packet[2:], 0x0800) //Protocol type IPv4
+	packet[4], packet[5] = 6, 4                    //Address lengths
+	binary.BigEndian.PutUint16(packet[6:], 1)      //Request
+	copy(packet[8:14], hw)
+	copy(packet[14:18], src.To4())
+	copy(packet[24:28], target.To4()) //Target hardware address stays zero
+	return packet
+}
+
+// isARPReply reports whether packet is an Ethernet ARP reply sent by target.
+func isARPReply(packet []byte, target net.IP) bool {
+	if len(packet) < 28 {
+		return false
+	}
+	return binary.BigEndian.Uint16(packet[0:]) == 1 &&
+		binary.BigEndian.Uint16(packet[2:]) == 0x0800 &&
+		packet[4] == 6 && packet[5] == 4 &&
+		binary.BigEndian.Uint16(packet[6:]) == 2 &&
+		bytes.Equal(packet[14:18], target.To4())
+}
+
+// arpCached reports whether the kernel ARP table holds a completed entry for
+// ip. It is the fallback without the privileges to send ARP requests; only
+// Linux exposes the table, elsewhere it always reports false.
+func arpCached(ip net.IP) bool {
+	data, err := os.ReadFile(arpTable)
+	if err != nil {
+		return false
+	}
+	return arpTableHas(string(data), ip)
+}
+
+// arpTableHas reports whether table, in the format of /proc/net/arp, holds a
+// completed entry for ip. Incomplete entries have no flags and permanent ones
+// added by hand prove nothing about the host.
+func arpTableHas(table string, ip net.IP) bool {
+	lines := strings.Split(table, "\n")
+	for _, line := range lines[1:] {
+		fields := strings.Fields(line)
+		if len(fields) < 4 || fields[0] != ip.String() {
+			continue
+		}
+		return fields[2] == "0x2" && fields[3] != "00:00:00:00:00:00"
+	}
+	return false
+}
@@ -0,0 +1,75 @@
+//go:build linux
+
+package discovery
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+const ethARP = 0x0806 //EtherType of ARP
+
+// htons converts a 16 bit value to network byte order for sockaddr fields.
+func htons(value uint16) uint16 {
+	return value<<8 | value>>8
+}
+
+// arpAvailable reports whether ARP requests can be sent, which takes a packet
+// socket and so root or CAP_NET_RAW.
+func arpAvailable() bool {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM, int(htons(ethARP)))
+	if err != nil {
+		return false
+	}
+	syscall.Close(fd)
+	return true
+}
+
+// arpPing broadcasts an ARP request for ip on the interface of local and waits
+// up to timeout for the reply, resending the request once halfway.
+func arpPing(ctx context.Context, local localNet, ip net.IP, timeout time.Duration) bool {
+	fd, err := syscall.Socket(syscall.AF_PACKET, syscall.SOCK_DGRAM, int(htons(ethARP)))
+	if err != nil {
+		return false
+	}
+	defer syscall.Close(fd)
+	link := &syscall.SockaddrLinklayer{Protocol: htons(ethARP), Ifindex: local.iface.Index}
+	if err := syscall.Bind(fd, link); err != nil {
+		return false
+	}
+	//Wake up regularly to notice cancellation
+	wake := syscall.NsecToTimeval(int64(50 * time.Millisecond))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &wake); err != nil {
+		return false
+	}
+
+	request := arpRequest(local.iface.HardwareAddr, local.ipnet.IP, ip)
+	broadcast := &syscall.SockaddrLinklayer{Protocol: htons(ethARP), Ifindex: local.iface.Index, Halen: 6}
+	copy(broadcast.Addr[:], []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	deadline := time.Now().Add(timeout)
+	resend := time.Now().Add(timeout / 2)
+	if err := syscall.Sendto(fd, request, 0, broadcast); err != nil {
+		return false
+	}
+	buf := make([]byte, 128)
+	for ctx.Err() == nil && time.Now().Before(deadline) {
+		if !resend.IsZero() && time.Now().After(resend) {
+			syscall.Sendto(fd, request, 0, broadcast)
+			resend = time.Time{}
+		}
+		n, _, err := syscall.Recvfrom(fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return false
+		}
+		if isARPReply(buf[:n], ip) {
+			return true
+		}
+	}
+	return false
+}
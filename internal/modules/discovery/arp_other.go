@@ -0,0 +1,18 @@
+//go:build !linux
+
+package discovery
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// arpAvailable reports false, ARP requests are only sent on Linux.
+func arpAvailable() bool {
+	return false
+}
+
+func arpPing(ctx context.Context, local localNet, ip net.IP, timeout time.Duration) bool {
+	return false
+}
@@ -0,0 +1,70 @@
+package discovery
+
+import (
+	"net"
+	"testing"
+)
+
+const testARPTable = `IP address       HW type     Flags       HW address            Mask     Device
+192.0.2.1        0x1         0x2         02:fc:00:00:00:05     *        eth0
+192.0.2.7        0x1         0x0         00:00:00:00:00:00     *        eth0
+192.0.2.8        0x1         0x6         02:fc:00:00:00:08     *        eth0
+192.0.2.9        0x1         0x2         00:00:00:00:00:00     *        eth0
+192.0.2.10       0x1         0x2         02:fc:00:00:00:0a     *        eth0
+`
+
+func TestARPTableHas(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"192.0.2.1", true},   //Completed
+		{"192.0.2.7", false},  //Incomplete, asked but never answered
+		{"192.0.2.8", false},  //Permanent, added by hand
+		{"192.0.2.9", false},  //No hardware address
+		{"192.0.2.10", true},  //Completed
+		{"192.0.2.11", false}, //Missing
+		{"192.0.2.100", false},
+	}
+	for _, test := range tests {
+		if got := arpTableHas(testARPTable, net.ParseIP(test.ip)); got != test.want {
+			t.Errorf("arpTableHas(%s) = %v, want %v", test.ip, got, test.want)
+		}
+	}
+	if arpTableHas("", net.ParseIP("192.0.2.1")) {
+		t.Error("empty table has an entry")
+	}
+}
+
+func TestARPPackets(t *testing.T) {
+	hw := net.HardwareAddr{0x02, 0xfc, 0, 0, 0, 1}
+	src, target := net.ParseIP("192.0.2.2"), net.ParseIP("192.0.2.1")
+	request := arpRequest(hw, src, target)
+	want := []byte{
+		0, 1, 8, 0, 6, 4, 0, 1, //Ethernet, IPv4, request
+		0x02, 0xfc, 0, 0, 0, 1, 192, 0, 2, 2, //Sender
+		0, 0, 0, 0, 0, 0, 192, 0, 2, 1, //Target
+	}
+	if string(request) != string(want) {
+		t.Errorf("arpRequest = % x, want % x", request, want)
+	}
+	if isARPReply(request, target) {
+		t.Error("request taken for a reply")
+	}
+
+	reply := []byte{
+		0, 1, 8, 0, 6, 4, 0, 2,
+		0x02, 0xfc, 0, 0, 0, 5, 192, 0, 2, 1,
+		0x02, 0xfc, 0, 0, 0, 1, 192, 0, 2, 2,
+		0, 0, //Ethernet padding
+	}
+	if !isARPReply(reply, target) {
+		t.Error("reply from the target not recognized")
+	}
+	if isARPReply(reply, net.ParseIP("192.0.2.3")) {
+		t.Error("reply from another host taken for the target's")
+	}
+	if isARPReply(reply[:20], target) {
+		t.Error("truncated reply accepted")
+	}
+}
@@ -0,0 +1,246 @@
+// Package discovery tells live hosts from dead ones before they are port
+// scanned. Hosts are pinged with ICMP echo requests and raw TCP SYN and ACK
+// segments to common ports, or TCP connections to them without the privileges
+// for raw sockets. Hosts on a directly connected subnet are also sent ARP
+// requests, which they answer even when every IP packet is firewalled; without
+// the privileges only the kernel ARP table is consulted.
+package discovery
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	synscan "github.com/efecankaya/go-port-scanner/internal/modules/syn_scan"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+	"golang.org/x/net/ipv6"
+)
+
+const (
+	MethodARP      = "arp"       //Answered an ARP request on a local subnet
+	MethodARPCache = "arp-cache" //Completed entry in the kernel ARP table
+	MethodICMP     = "icmp"      //Answered an ICMP echo request
+	MethodSYN      = "syn"       //Answered a raw TCP SYN
+	MethodACK      = "ack"       //Answered a raw TCP ACK
+	MethodTCP      = "tcp"       //Accepted or refused a TCP connection
+	MethodLoopback = "loopback"  //Loopback address, never probed
+)
+
+// DefaultPorts are the TCP ports pinged when none are given. A refused
+// connection proves the host is up just as well as an accepted one.
+var DefaultPorts = []int{80, 443, 22, 445, 3389}
+
+// Pinger probes hosts for liveness. It is safe for concurrent use.
+type Pinger struct {
+	timeout time.Duration
+	ports   []int
+	icmp4   string           //Network for ICMP echo over IPv4, empty if unavailable
+	icmp6   string           //Network for ICMP echo over IPv6, empty if unavailable
+	local   []localNet       //Directly connected IPv4 subnets
+	arp     bool             //ARP requests can be sent
+	raw     *synscan.Scanner //Raw socket for SYN and ACK pings, nil if unavailable
+	id      uint32           //Echo identifier, filters replies on raw sockets
+	seq     atomic.Uint32
+}
+
+// New creates a Pinger waiting up to timeout for every probe. Raw ICMP, TCP and
+// ARP sockets need root or CAP_NET_RAW. Without them unprivileged ping sockets
+// are used where the kernel allows them and ICMP is skipped otherwise, TCP
+// pings fall back to connections and ARP to reading the kernel table. The
+// Pinger must be closed after use.
+func New(timeout time.Duration, ports []int) *Pinger {
+	if len(ports) == 0 {
+		ports = DefaultPorts
+	}
+	p := &Pinger{
+		timeout: timeout,
+		ports:   ports,
+		icmp4:   icmpNetwork("ip4:icmp", "udp4", "0.0.0.0"),
+		icmp6:   icmpNetwork("ip6:ipv6-icmp", "udp6", "::"),
+		id:      uint32(rand.Intn(0xffff)),
+	}
+	if raw, err := synscan.New(); err == nil {
+		p.raw = raw
+	}
+	p.local = localNets()
+	p.arp = len(p.local) > 0 && arpAvailable()
+	return p
+}
+
+// Close releases the raw socket of the SYN and ACK pings.
+func (p *Pinger) Close() error {
+	if p.raw == nil {
+		return nil
+	}
+	return p.raw.Close()
+}
+
+// icmpNetwork returns the first of the raw and unprivileged networks an ICMP
+// socket can be opened on.
+func icmpNetwork(raw, unprivileged, address string) string {
+	for _, network := range []string{raw, unprivileged} {
+		if conn, err := icmp.ListenPacket(network, address); err == nil {
+			conn.Close()
+			return network
+		}
+	}
+	return ""
+}
+
+// Alive reports whether host answered any probe and the method that proved
+// it. Hosts that are not IP addresses are reported alive since they were
+// resolved on purpose.
+func (p *Pinger) Alive(ctx context.Context, host string) (bool, string) {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return true, ""
+	}
+	if ip.IsLoopback() {
+		return true, MethodLoopback
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	found := make(chan string, 2*len(p.ports)+2)
+	var wg sync.WaitGroup
+	probe := func(method string, fn func(context.Context, net.IP) bool) {
+		defer wg.Done()
+		if fn(ctx, ip) {
+			found <- method
+			cancel() //First answer is enough
+		}
+	}
+	wg.Add(1)
+	go probe(MethodICMP, p.ping)
+	local, is_local := p.localNet(ip)
+	if is_local && p.arp {
+		wg.Add(1)
+		go probe(MethodARP, func(ctx context.Context, ip net.IP) bool { return arpPing(ctx, local, ip, p.timeout) })
+	}
+	for _, port := range p.ports {
+		port := port
+		if p.raw != nil && ip.To4() != nil {
+			wg.Add(2)
+			go probe(MethodSYN, func(ctx context.Context, ip net.IP) bool { return answered(p.raw.Probe(ctx, ip, port, p.timeout)) })
+			go probe(MethodACK, func(ctx context.Context, ip net.IP) bool { return answered(p.raw.ProbeACK(ctx, ip, port, p.timeout)) })
+			continue
+		}
+		wg.Add(1)
+		go probe(MethodTCP, func(ctx context.Context, ip net.IP) bool { return p.connect(ctx, ip, port) })
+	}
+	wg.Wait()
+	close(found)
+	if method, ok := <-found; ok {
+		return true, method
+	}
+	if is_local && !p.arp && arpCached(ip) {
+		return true, MethodARPCache
+	}
+	return false, ""
+}
+
+// ping sends an ICMP echo request to ip and waits for the matching reply.
+func (p *Pinger) ping(ctx context.Context, ip net.IP) bool {
+	network, address := p.icmp4, "0.0.0.0"
+	var request icmp.Type = ipv4.ICMPTypeEcho
+	var reply icmp.Type = ipv4.ICMPTypeEchoReply
+	protocol := 1
+	if ip.To4() == nil {
+		network, address = p.icmp6, "::"
+		request, reply, protocol = ipv6.ICMPTypeEchoRequest, ipv6.ICMPTypeEchoReply, 58
+	}
+	if network == "" {
+		return false
+	}
+	conn, err := icmp.ListenPacket(network, address)
+	if err != nil {
+		return false
+	}
+	defer conn.Close()
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	seq := int(p.seq.Add(1) & 0xffff)
+	message := icmp.Message{Type: request, Body: &icmp.Echo{ID: int(p.id), Seq: seq, Data: []byte("go-port-scanner")}}
+	data, err := message.Marshal(nil)
+	if err != nil {
+		return false
+	}
+	var dst net.Addr = &net.IPAddr{IP: ip}
+	unprivileged := strings.HasPrefix(network, "udp")
+	if unprivileged {
+		dst = &net.UDPAddr{IP: ip}
+	}
+	conn.SetDeadline(time.Now().Add(p.timeout))
+	if _, err := conn.WriteTo(data, dst); err != nil {
+		return false
+	}
+
+	buf := make([]byte, 1500)
+	for {
+		n, peer, err := conn.ReadFrom(buf)
+		if err != nil {
+			return false
+		}
+		answer, err := icmp.ParseMessage(protocol, buf[:n])
+		if err != nil || answer.Type != reply || !sameIP(peer, ip) {
+			continue
+		}
+		echo, ok := answer.Body.(*icmp.Echo)
+		if !ok || echo.Seq != seq {
+			continue
+		}
+		if unprivileged || echo.ID == int(p.id) { //The kernel rewrites the identifier of ping sockets
+			return true
+		}
+	}
+}
+
+// connect opens a TCP connection to ip:port. The host is up if it accepts or
+// actively refuses the connection.
+func (p *Pinger) connect(ctx context.Context, ip net.IP, port int) bool {
+	dialer := net.Dialer{Timeout: p.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip.String(), strconv.Itoa(port)))
+	if err == nil {
+		conn.Close()
+		return true
+	}
+	return errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET)
+}
+
+// answered reports whether a raw SYN or ACK got any reply. A SYN-ACK is never
+// acknowledged, the kernel resets the half-open connection itself.
+func answered(reply synscan.Reply, err error) bool {
+	return err == nil && reply.State != synscan.StateFiltered
+}
+
+// localNet returns the directly connected subnet holding ip.
+func (p *Pinger) localNet(ip net.IP) (localNet, bool) {
+	if ip.To4() == nil {
+		return localNet{}, false
+	}
+	for _, local := range p.local {
+		if local.ipnet.Contains(ip) {
+			return local, true
+		}
+	}
+	return localNet{}, false
+}
+
+func sameIP(addr net.Addr, ip net.IP) bool {
+	switch addr := addr.(type) {
+	case *net.IPAddr:
+		return addr.IP.Equal(ip)
+	case *net.UDPAddr:
+		return addr.IP.Equal(ip)
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package discovery
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// listenPorts returns a port with a listener and one without.
+func listenPorts(t *testing.T) (open, closed int) {
+	t.Helper()
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	spare, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	spare.Close()
+	return listener.Addr().(*net.TCPAddr).Port, spare.Addr().(*net.TCPAddr).Port
+}
+
+func TestConnect(t *testing.T) {
+	open, closed := listenPorts(t)
+	p := &Pinger{timeout: time.Second}
+	ip := net.IPv4(127, 0, 0, 1)
+	if !p.connect(context.Background(), ip, open) {
+		t.Error("accepted connection not taken as alive")
+	}
+	if !p.connect(context.Background(), ip, closed) {
+		t.Error("refused connection not taken as alive")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if p.connect(ctx, ip, open) {
+		t.Error("cancelled connection taken as alive")
+	}
+}
+
+// ownAddress returns a non-loopback IPv4 address of this machine.
+func ownAddress(t *testing.T) net.IP {
+	t.Helper()
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.To4() != nil && !ipnet.IP.IsLoopback() {
+			return ipnet.IP
+		}
+	}
+	t.Skip("no non-loopback IPv4 address")
+	return nil
+}
+
+// TestAliveConnectFallback pings through TCP connections only, as without
+// the privileges for raw sockets.
+func TestAliveConnectFallback(t *testing.T) {
+	_, closed := listenPorts(t)
+	p := &Pinger{timeout: time.Second, ports: []int{closed}}
+	if alive, method := p.Alive(context.Background(), "127.0.0.1"); !alive || method != MethodLoopback {
+		t.Errorf("Alive(127.0.0.1) = %v, %q, want loopback", alive, method)
+	}
+	if alive, method := p.Alive(context.Background(), "scanme.example"); !alive || method != "" {
+		t.Errorf("Alive(hostname) = %v, %q, want alive without probing", alive, method)
+	}
+
+	//Nothing listens on the port, the refused connection is the answer
+	ip := ownAddress(t)
+	if alive, method := p.Alive(context.Background(), ip.String()); !alive || method != MethodTCP {
+		t.Errorf("Alive(%s) = %v, %q, want alive by %s", ip, alive, method, MethodTCP)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if alive, _ := p.Alive(ctx, ip.String()); alive {
+		t.Errorf("Alive(%s) with a cancelled context reported alive", ip)
+	}
+}
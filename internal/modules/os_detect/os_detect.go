@@ -0,0 +1,310 @@
+// Package osdetect infers the operating system of a host from what a port
+// scan already collects: banners, identified products, HTTP Server headers and
+// the TTL and TCP window of SYN scan replies. Every port yields weighted clues,
+// a Host adds up the clues of all its ports into a single Guess.
+package osdetect
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Families reported by Guess.
+const (
+	FamilyLinux   = "Linux"
+	FamilyWindows = "Windows"
+	FamilyBSD     = "BSD"
+	FamilyMacOS   = "macOS"
+	FamilyNetwork = "Network device" //Routers, switches and other embedded stacks
+)
+
+// Guess is the operating system inferred for a host.
+type Guess struct {
+	Family     string   `json:"family"`             //Operating system family, one of the Family constants
+	Version    string   `json:"version,omitempty"`  //Distribution or release, when a clue named one
+	Confidence int      `json:"confidence"`         //0-100
+	Evidence   []string `json:"evidence,omitempty"` //Clues supporting the family
+}
+
+// String formats g as "Family Version (Confidence%)", empty without a family.
+func (g Guess) String() string {
+	if g.Family == "" {
+		return ""
+	}
+	name := g.Family
+	if g.Version != "" {
+		name += " " + g.Version
+	}
+	return fmt.Sprintf("%s (%d%%)", name, g.Confidence)
+}
+
+// Evidence is what a single port tells about its host.
+type Evidence struct {
+	Banner  string //Printable banner or probe response
+	Product string //Product identified on the port
+	Version string //Version of the product
+	Server  string //HTTP Server header
+	TTL     int    //IP TTL of a SYN scan reply, 0 if unknown
+	Window  int    //TCP window of a SYN scan reply, 0 if unknown
+}
+
+// clue is a weighted hint towards a family.
+type clue struct {
+	family  string
+	version string //Empty if the clue does not tell the release
+	weight  int    //Strength of the hint, a weight of 100 is conclusive alone
+	source  string //Kind of evidence, such as ssh or ttl
+	value   string //What the evidence said, clues with the same source and value count once per host
+}
+
+// ttlOnlyConfidence caps the confidence of a guess resting on TTL and window
+// values alone, many systems share them.
+const ttlOnlyConfidence = 30
+
+// String formats c as evidence of a guess.
+func (c clue) String() string {
+	return c.source + " " + c.value
+}
+
+// distros maps the distribution tags that packagers append to banners and
+// Server headers to a family.
+var distros = []struct {
+	pattern *regexp.Regexp
+	family  string
+	name    string
+}{
+	{regexp.MustCompile(`(?i)ubuntu`), FamilyLinux, "Ubuntu"},
+	{regexp.MustCompile(`(?i)raspbian`), FamilyLinux, "Raspbian"},
+	{regexp.MustCompile(`(?i)debian|\+deb\d+u`), FamilyLinux, "Debian"},
+	{regexp.MustCompile(`(?i)centos`), FamilyLinux, "CentOS"},
+	{regexp.MustCompile(`(?i)red ?hat|rhel`), FamilyLinux, "Red Hat"},
+	{regexp.MustCompile(`(?i)fedora`), FamilyLinux, "Fedora"},
+	{regexp.MustCompile(`(?i)alpine`), FamilyLinux, "Alpine"},
+	{regexp.MustCompile(`(?i)suse`), FamilyLinux, "SUSE"},
+	{regexp.MustCompile(`(?i)amazon ?linux|amzn`), FamilyLinux, "Amazon Linux"},
+	{regexp.MustCompile(`(?i)freebsd`), FamilyBSD, "FreeBSD"},
+	{regexp.MustCompile(`(?i)openbsd`), FamilyBSD, "OpenBSD"},
+	{regexp.MustCompile(`(?i)netbsd`), FamilyBSD, "NetBSD"},
+	{regexp.MustCompile(`(?i)darwin|mac ?os`), FamilyMacOS, ""},
+	{regexp.MustCompile(`(?i)\bwin(?:32|64|dows)\b`), FamilyWindows, ""},
+}
+
+// sshReleases maps the OpenSSH version and packaging of a distribution to the
+// release shipping it.
+var sshReleases = map[string]string{
+	"Ubuntu 9.6p1": "24.04",
+	"Ubuntu 9.3p1": "23.10",
+	"Ubuntu 8.9p1": "22.04",
+	"Ubuntu 8.2p1": "20.04",
+	"Ubuntu 7.6p1": "18.04",
+	"Ubuntu 7.2p2": "16.04",
+	"Debian 9.2p1": "12",
+	"Debian 8.4p1": "11",
+	"Debian 7.9p1": "10",
+	"Debian 7.4p1": "9",
+}
+
+// debianUpdate matches the Debian security update suffix naming the release,
+// as in OpenSSH_9.2p1 Debian-2+deb12u2.
+var debianUpdate = regexp.MustCompile(`\+deb(\d+)u`)
+
+var sshBanner = regexp.MustCompile(`^SSH-[\d.]+-(\S+)(?:\s+(.*))?`)
+
+// iisReleases maps IIS versions to the Windows releases that ship them.
+var iisReleases = map[string]string{
+	"5.0":  "2000",
+	"5.1":  "XP",
+	"6.0":  "Server 2003",
+	"7.0":  "Server 2008",
+	"7.5":  "7 / Server 2008 R2",
+	"8.0":  "8 / Server 2012",
+	"8.5":  "8.1 / Server 2012 R2",
+	"10.0": "10 / Server 2016+",
+}
+
+var iisServer = regexp.MustCompile(`(?i)Microsoft-IIS/([\d.]+)`)
+
+// clues extracts the hints of a single port.
+func clues(e Evidence) []clue {
+	var found []clue
+	add := func(family, version string, weight int, source, value string) {
+		found = append(found, clue{family: family, version: version, weight: weight, source: source, value: value})
+	}
+
+	if m := sshBanner.FindStringSubmatch(e.Banner); m != nil {
+		software, comment := m[1], m[2]
+		switch {
+		case strings.Contains(software, "OpenSSH_for_Windows"):
+			add(FamilyWindows, "", 80, "ssh", software)
+		case strings.HasPrefix(software, "OpenSSH_"):
+			version := strings.TrimPrefix(software, "OpenSSH_")
+			matched := false
+			for _, distro := range distros {
+				if distro.name == "" || !distro.pattern.MatchString(software+" "+comment) {
+					continue
+				}
+				release := distro.name
+				if number, ok := sshReleases[distro.name+" "+version]; ok {
+					release += " " + number
+				} else if m := debianUpdate.FindStringSubmatch(comment); m != nil && distro.name == "Debian" {
+					release += " " + m[1]
+				}
+				add(distro.family, release, 70, "ssh", strings.TrimSpace(software+" "+comment))
+				matched = true
+				break
+			}
+			if !matched { //Plain OpenSSH is almost always some Unix
+				add(FamilyLinux, "", 25, "ssh", software)
+			}
+		}
+	} else if e.Banner != "" {
+		for _, distro := range distros {
+			if distro.pattern.MatchString(e.Banner) {
+				add(distro.family, distro.name, 30, "banner", firstLine(e.Banner))
+				break
+			}
+		}
+	}
+
+	if e.Server != "" {
+		if m := iisServer.FindStringSubmatch(e.Server); m != nil {
+			add(FamilyWindows, iisReleases[m[1]], 70, "http server", e.Server)
+		} else if strings.Contains(e.Server, "Microsoft-HTTPAPI") {
+			add(FamilyWindows, "", 50, "http server", e.Server)
+		} else {
+			for _, distro := range distros {
+				if distro.pattern.MatchString(e.Server) {
+					add(distro.family, distro.name, 50, "http server", e.Server)
+					break
+				}
+			}
+		}
+	}
+
+	switch product := e.Product; {
+	case product == "Microsoft Terminal Services":
+		add(FamilyWindows, "", 60, "rdp", product)
+	case product == "Microsoft SMB": //Samba answers the same negotiation
+		add(FamilyWindows, "", 30, "smb", product)
+	case strings.HasPrefix(product, "Microsoft"):
+		add(FamilyWindows, "", 50, "product", strings.TrimSpace(product+" "+e.Version))
+	}
+
+	if e.TTL > 0 { //Keyed by the initial TTL so hops do not count as new evidence
+		switch initial := initialTTL(e.TTL); {
+		case initial == 64 && e.Window == 65535:
+			add(FamilyBSD, "", 20, "ttl", fmt.Sprintf("%d window %d", initial, e.Window))
+			add(FamilyMacOS, "", 20, "ttl", fmt.Sprintf("%d window %d", initial, e.Window))
+		case initial == 64:
+			add(FamilyLinux, "", 20, "ttl", strconv.Itoa(initial))
+		case initial == 128:
+			add(FamilyWindows, "", 25, "ttl", strconv.Itoa(initial))
+		case initial == 255:
+			add(FamilyNetwork, "", 20, "ttl", strconv.Itoa(initial))
+		}
+	}
+	return found
+}
+
+func firstLine(text string) string {
+	line, _, _ := strings.Cut(text, "\n")
+	line = strings.TrimSpace(line)
+	if len(line) > 60 {
+		line = line[:60]
+	}
+	return line
+}
+
+// initialTTL rounds a received TTL up to the common initial value it most
+// likely started from.
+func initialTTL(ttl int) int {
+	switch {
+	case ttl <= 32:
+		return 32
+	case ttl <= 64:
+		return 64
+	case ttl <= 128:
+		return 128
+	}
+	return 255
+}
+
+// Host accumulates the evidence of every port of a host. It is safe for
+// concurrent use.
+type Host struct {
+	mu    sync.Mutex
+	clues []clue
+}
+
+// Add records the evidence of a port and returns the guess so far. Clues
+// already seen on another port of the host are not counted again.
+func (h *Host) Add(e Evidence) Guess {
+	found := clues(e)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, c := range found {
+		if !slices.ContainsFunc(h.clues, func(known clue) bool {
+			return known.family == c.family && known.source == c.source && known.value == c.value
+		}) {
+			h.clues = append(h.clues, c)
+		}
+	}
+	return guess(h.clues)
+}
+
+// Guess returns the guess for the evidence added so far.
+func (h *Host) Guess() Guess {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return guess(h.clues)
+}
+
+// guess picks the family with the largest total weight. Its confidence is the
+// total capped at 100 and scaled down by the share of weight pointing to other
+// families, and capped at ttlOnlyConfidence when only TTL values back it. The
+// version comes from the heaviest clue naming one.
+func guess(found []clue) Guess {
+	scores := make(map[string]int)
+	total := 0
+	for _, c := range found {
+		scores[c.family] += c.weight
+		total += c.weight
+	}
+	if total == 0 {
+		return Guess{}
+	}
+	families := make([]string, 0, len(scores))
+	for family := range scores {
+		families = append(families, family)
+	}
+	sort.Slice(families, func(i, j int) bool {
+		if scores[families[i]] != scores[families[j]] {
+			return scores[families[i]] > scores[families[j]]
+		}
+		return families[i] < families[j]
+	})
+	best := families[0]
+
+	result := Guess{Family: best, Confidence: min(100, scores[best]) * scores[best] / total}
+	version_weight := 0
+	ttl_only := true
+	for _, c := range found {
+		if c.family != best {
+			continue
+		}
+		if c.version != "" && (c.weight > version_weight || len(c.version) > len(result.Version) && c.weight == version_weight) {
+			result.Version, version_weight = c.version, c.weight
+		}
+		ttl_only = ttl_only && c.source == "ttl"
+		result.Evidence = append(result.Evidence, c.String())
+	}
+	if ttl_only {
+		result.Confidence = min(result.Confidence, ttlOnlyConfidence)
+	}
+	return result
+}
@@ -0,0 +1,167 @@
+package osdetect
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestHost(t *testing.T) {
+	tests := []struct {
+		name     string
+		ports    []Evidence
+		want     Guess
+		evidence []string //Compared when set
+	}{
+		{
+			name:  "nothing known",
+			ports: []Evidence{{Banner: "+OK ready"}},
+			want:  Guess{},
+		},
+		{
+			name:     "ttl only",
+			ports:    []Evidence{{TTL: 57}},
+			want:     Guess{Family: FamilyLinux, Confidence: 20},
+			evidence: []string{"ttl 64"},
+		},
+		{
+			name:  "ttl and window shared by BSD and macOS",
+			ports: []Evidence{{TTL: 64, Window: 65535}},
+			want:  Guess{Family: FamilyBSD, Confidence: 10},
+		},
+		{
+			name:     "ssh banner with distribution",
+			ports:    []Evidence{{Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"}},
+			want:     Guess{Family: FamilyLinux, Version: "Ubuntu 22.04", Confidence: 70},
+			evidence: []string{"ssh OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"},
+		},
+		{
+			name:  "debian security update",
+			ports: []Evidence{{Banner: "SSH-2.0-OpenSSH_9.9p1 Debian-3+deb13u1"}},
+			want:  Guess{Family: FamilyLinux, Version: "Debian 13", Confidence: 70},
+		},
+		{
+			name:  "plain openssh",
+			ports: []Evidence{{Banner: "SSH-2.0-OpenSSH_9.7"}},
+			want:  Guess{Family: FamilyLinux, Confidence: 25},
+		},
+		{
+			name:  "openssh for windows",
+			ports: []Evidence{{Banner: "SSH-2.0-OpenSSH_for_Windows_8.1"}},
+			want:  Guess{Family: FamilyWindows, Confidence: 80},
+		},
+		{
+			name:     "other banner",
+			ports:    []Evidence{{Banner: "220 mail.example.com ESMTP Postfix (Debian/GNU)\r\n"}},
+			want:     Guess{Family: FamilyLinux, Version: "Debian", Confidence: 30},
+			evidence: []string{"banner 220 mail.example.com ESMTP Postfix (Debian/GNU)"},
+		},
+		{
+			name:  "iis server header",
+			ports: []Evidence{{Server: "Microsoft-IIS/8.5"}},
+			want:  Guess{Family: FamilyWindows, Version: "8.1 / Server 2012 R2", Confidence: 70},
+		},
+		{
+			name:     "banner and ttl agree",
+			ports:    []Evidence{{Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6", TTL: 63}},
+			want:     Guess{Family: FamilyLinux, Version: "Ubuntu 22.04", Confidence: 90},
+			evidence: []string{"ssh OpenSSH_8.9p1 Ubuntu-3ubuntu0.6", "ttl 64"},
+		},
+		{
+			name: "banner outweighs a conflicting ttl",
+			ports: []Evidence{
+				{Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"},
+				{TTL: 120},
+			},
+			want: Guess{Family: FamilyLinux, Version: "Ubuntu 22.04", Confidence: 70 * 70 / 95},
+		},
+		{
+			name: "capped at 100",
+			ports: []Evidence{
+				{Server: "Microsoft-IIS/10.0", TTL: 127},
+				{Product: "Microsoft Terminal Services", TTL: 127},
+			},
+			want:     Guess{Family: FamilyWindows, Version: "10 / Server 2016+", Confidence: 100},
+			evidence: []string{"http server Microsoft-IIS/10.0", "ttl 128", "rdp Microsoft Terminal Services"},
+		},
+		{
+			name: "same clue on several ports counts once",
+			ports: []Evidence{
+				{Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6", TTL: 64},
+				{Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6", TTL: 61},
+				{TTL: 50},
+			},
+			want:     Guess{Family: FamilyLinux, Version: "Ubuntu 22.04", Confidence: 90},
+			evidence: []string{"ssh OpenSSH_8.9p1 Ubuntu-3ubuntu0.6", "ttl 64"},
+		},
+	}
+	for _, test := range tests {
+		var host Host
+		var got Guess
+		for _, e := range test.ports {
+			got = host.Add(e)
+		}
+		if !reflect.DeepEqual(got, host.Guess()) {
+			t.Errorf("%s: Add = %+v, Guess = %+v, want the same", test.name, got, host.Guess())
+		}
+		evidence := got.Evidence
+		got.Evidence = nil
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: guess = %+v, want %+v", test.name, got, test.want)
+		}
+		if test.evidence != nil && !reflect.DeepEqual(evidence, test.evidence) {
+			t.Errorf("%s: evidence = %q, want %q", test.name, evidence, test.evidence)
+		}
+	}
+}
+
+func TestTTLOnlyCap(t *testing.T) {
+	found := []clue{
+		{family: FamilyWindows, weight: 25, source: "ttl", value: "128"},
+		{family: FamilyWindows, weight: 25, source: "ttl", value: "128 window 8192"},
+	}
+	if got := guess(found); got.Family != FamilyWindows || got.Confidence != ttlOnlyConfidence {
+		t.Errorf("guess = %+v, want Windows capped at %d", got, ttlOnlyConfidence)
+	}
+	found = append(found, clue{family: FamilyWindows, weight: 50, source: "http server", value: "Microsoft-HTTPAPI/2.0"})
+	if got := guess(found); got.Confidence != 100 {
+		t.Errorf("guess with a banner clue = %+v, want the cap lifted", got)
+	}
+}
+
+func TestInitialTTL(t *testing.T) {
+	for ttl, want := range map[int]int{1: 32, 32: 32, 33: 64, 64: 64, 100: 128, 128: 128, 129: 255, 255: 255} {
+		if got := initialTTL(ttl); got != want {
+			t.Errorf("initialTTL(%d) = %d, want %d", ttl, got, want)
+		}
+	}
+}
+
+func TestGuessString(t *testing.T) {
+	tests := map[string]Guess{
+		"":                         {},
+		"Linux (20%)":              {Family: FamilyLinux, Confidence: 20},
+		"Linux Ubuntu 22.04 (90%)": {Family: FamilyLinux, Version: "Ubuntu 22.04", Confidence: 90},
+	}
+	for want, g := range tests {
+		if got := g.String(); got != want {
+			t.Errorf("String(%+v) = %q, want %q", g, got, want)
+		}
+	}
+}
+
+func TestHostConcurrent(t *testing.T) {
+	var host Host
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			host.Add(Evidence{Banner: "SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13", TTL: 64})
+		}()
+	}
+	wg.Wait()
+	if got := host.Guess(); got.Version != "Ubuntu 24.04" || got.Confidence != 90 || len(got.Evidence) != 2 {
+		t.Errorf("guess = %+v, want the clues counted once", got)
+	}
+}
@@ -0,0 +1,154 @@
+// Package pageinfo extracts the fields used to group identical web pages
+// across hosts: the title, the declared favicon and the hashes of the body and
+// favicon.
+package pageinfo
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"math/bits"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Document holds the parts of an HTML page that identify it.
+type Document struct {
+	Title string //Text of the first title tag
+	Icon  string //href of the first link tag declaring an icon
+}
+
+// Favicon identifies the icon of a site.
+type Favicon struct {
+	URL    string `json:"url"`    //Where the icon was downloaded from
+	MMH3   int32  `json:"mmh3"`   //Shodan style hash, see FaviconHash
+	SHA256 string `json:"sha256"` //Hex encoded SHA-256 of the icon
+	Size   int    `json:"size"`   //Bytes of the icon
+}
+
+// Parse extracts the title and icon link of an HTML body.
+func Parse(body string) Document {
+	var doc Document
+	in_title, title_done := false, false
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			doc.Title = strings.Join(strings.Fields(doc.Title), " ")
+			return doc
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				in_title = !title_done
+			case "link":
+				var rel, href string
+				for _, attr := range token.Attr {
+					switch attr.Key {
+					case "rel":
+						rel = strings.ToLower(attr.Val)
+					case "href":
+						href = attr.Val
+					}
+				}
+				for _, kind := range strings.Fields(rel) {
+					if (kind == "icon" || kind == "apple-touch-icon") && doc.Icon == "" && href != "" {
+						doc.Icon = href
+					}
+				}
+			}
+		case html.TextToken:
+			if in_title {
+				doc.Title += string(tokenizer.Text())
+			}
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "title" && in_title {
+				in_title, title_done = false, true
+			}
+		}
+	}
+}
+
+// IconURL resolves the icon declared by doc against the URL of the page,
+// falling back to /favicon.ico. Only icons on the scheme and host of the page
+// are used, the request goes to the scanned address and would fetch another
+// server's icon from it. Icons embedded as data URLs are skipped.
+func IconURL(page string, doc Document) string {
+	base, err := url.Parse(page)
+	if err != nil {
+		return ""
+	}
+	if doc.Icon != "" && !strings.HasPrefix(doc.Icon, "data:") {
+		if icon, err := base.Parse(doc.Icon); err == nil && icon.Scheme == base.Scheme && strings.EqualFold(icon.Host, base.Host) {
+			return icon.String()
+		}
+	}
+	icon, _ := base.Parse("/favicon.ico")
+	return icon.String()
+}
+
+// SHA256 returns the hex encoded SHA-256 of data.
+func SHA256(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// FaviconHash returns the favicon hash used by Shodan: the signed MMH3 of the
+// base64 encoding of data, wrapped at 76 characters with a newline after every
+// line like Python's base64.encodebytes.
+func FaviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	var wrapped strings.Builder
+	for len(encoded) > 0 {
+		line := encoded[:min(len(encoded), 76)]
+		wrapped.WriteString(line)
+		wrapped.WriteByte('\n')
+		encoded = encoded[len(line):]
+	}
+	return int32(murmur3([]byte(wrapped.String()), 0))
+}
+
+// murmur3 is the 32 bit x86 variant of MurmurHash3.
+func murmur3(data []byte, seed uint32) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+	h := seed
+	blocks := len(data) / 4
+	for i := 0; i < blocks; i++ {
+		k := binary.LittleEndian.Uint32(data[i*4:])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+	}
+	tail := data[blocks*4:]
+	var k uint32
+	switch len(tail) {
+	case 3:
+		k ^= uint32(tail[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(tail[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(tail[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+	h ^= uint32(len(data))
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+	return h
+}
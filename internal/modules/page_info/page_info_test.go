@@ -0,0 +1,103 @@
+package pageinfo
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		body string
+		want Document
+	}{
+		{
+			body: "<html><head><title>\n  Admin   Console </title><link rel=\"stylesheet\" href=\"/a.css\"><link rel=\"Shortcut Icon\" href=\"/static/icon.png\"></head></html>",
+			want: Document{Title: "Admin Console", Icon: "/static/icon.png"},
+		},
+		{
+			body: "<title>First</title><title>Second</title><link rel=apple-touch-icon href=touch.png><link rel=icon href=later.ico>",
+			want: Document{Title: "First", Icon: "touch.png"},
+		},
+		{
+			body: "<link rel=icon href=\"\"><link rel=icon href=\"data:image/png;base64,AAAA\"/>",
+			want: Document{Icon: "data:image/png;base64,AAAA"},
+		},
+		{
+			body: "not html at all",
+			want: Document{},
+		},
+	}
+	for _, test := range tests {
+		if got := Parse(test.body); got != test.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", test.body, got, test.want)
+		}
+	}
+}
+
+func TestIconURL(t *testing.T) {
+	tests := []struct {
+		page string
+		icon string
+		want string
+	}{
+		{"http://10.0.0.1:8080/", "", "http://10.0.0.1:8080/favicon.ico"},
+		{"http://10.0.0.1:8080/app/", "img/icon.png", "http://10.0.0.1:8080/app/img/icon.png"},
+		{"http://10.0.0.1:8080/app/", "/icon.png", "http://10.0.0.1:8080/icon.png"},
+		{"https://example.com/", "https://EXAMPLE.com/icon.png", "https://EXAMPLE.com/icon.png"},
+		{"https://example.com/", "//example.com/icon.png", "https://example.com/icon.png"},
+		{"https://example.com/", "https://cdn.example.net/icon.png", "https://example.com/favicon.ico"},
+		{"https://example.com/", "//cdn.example.net/icon.png", "https://example.com/favicon.ico"},
+		{"https://example.com/", "http://example.com/icon.png", "https://example.com/favicon.ico"},
+		{"https://example.com:8443/", "https://example.com/icon.png", "https://example.com:8443/favicon.ico"},
+		{"https://example.com/", "data:image/png;base64,AAAA", "https://example.com/favicon.ico"},
+		{"https://example.com/", "javascript:alert(1)", "https://example.com/favicon.ico"},
+		{"%zz", "/icon.png", ""},
+	}
+	for _, test := range tests {
+		if got := IconURL(test.page, Document{Icon: test.icon}); got != test.want {
+			t.Errorf("IconURL(%q, %q) = %q, want %q", test.page, test.icon, got, test.want)
+		}
+	}
+}
+
+func TestMurmur3(t *testing.T) {
+	tests := []struct {
+		data string
+		seed uint32
+		want uint32
+	}{
+		{"", 0, 0},
+		{"", 1, 0x514e28b7},
+		{"", 0xffffffff, 0x81f16f39},
+		{"\x00\x00\x00\x00", 0, 0x2362f9de},
+		{"aaaa", 0x9747b28c, 0x5a97808a},
+		{"abc", 0x9747b28c, 0xc84a62dd},
+		{"Hello, world!", 0x9747b28c, 0x24884cba},
+		{"The quick brown fox jumps over the lazy dog", 0x9747b28c, 0x2fa826cd},
+	}
+	for _, test := range tests {
+		if got := murmur3([]byte(test.data), test.seed); got != test.want {
+			t.Errorf("murmur3(%q, %#x) = %#08x, want %#08x", test.data, test.seed, got, test.want)
+		}
+	}
+}
+
+// The expected hashes are mmh3.hash(base64.encodebytes(data)), the way Shodan
+// computes http.favicon.hash.
+func TestFaviconHash(t *testing.T) {
+	sequence := make([]byte, 100)
+	for i := range sequence {
+		sequence[i] = byte(i)
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want int32
+	}{
+		{"empty", nil, 0},
+		{"one full line", make([]byte, 57), 1993561383},
+		{"wrapped", sequence, -1165240594},
+	}
+	for _, test := range tests {
+		if got := FaviconHash(test.data); got != test.want {
+			t.Errorf("%s: FaviconHash = %d, want %d", test.name, got, test.want)
+		}
+	}
+}
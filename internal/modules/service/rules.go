@@ -0,0 +1,57 @@
+package service
+
+import "regexp"
+
+func rule(service, probe, pattern, product, version string) matchRule {
+	return matchRule{service: service, probe: probe, pattern: regexp.MustCompile(pattern), product: product, version: version}
+}
+
+// matchRules are tried in order, specific signatures before generic ones.
+var matchRules = []matchRule{
+	//Passive banners
+	rule("ssh", "", `^SSH-[\d.]+-OpenSSH_([\w.]+)`, "OpenSSH", "$1"),
+	rule("ssh", "", `^SSH-[\d.]+-dropbear_([\w.]+)`, "Dropbear sshd", "$1"),
+	rule("ssh", "", `^SSH-[\d.]+-Cisco-([\d.]+)`, "Cisco SSH", "$1"),
+	rule("ssh", "", `^SSH-[\d.]+-([^\s\r\n]+)`, "$1", ""),
+	rule("ftp", "", `^220[ -].*vsFTPd ([\d.]+)`, "vsftpd", "$1"),
+	rule("ftp", "", `^220[ -].*ProFTPD ([\d.]+)`, "ProFTPD", "$1"),
+	rule("ftp", "", `^220[ -].*FileZilla Server(?: version)? ?([\w.]*)`, "FileZilla ftpd", "$1"),
+	rule("ftp", "", `^220[ -].*Pure-FTPd`, "Pure-FTPd", ""),
+	rule("ftp", "", `^220[ -].*Microsoft FTP Service`, "Microsoft ftpd", ""),
+	rule("smtp", "", `^220[ -].*ESMTP Postfix`, "Postfix smtpd", ""),
+	rule("smtp", "", `^220[ -].*Exim ([\d.]+)`, "Exim smtpd", "$1"),
+	rule("smtp", "", `^220[ -].*Microsoft ESMTP MAIL Service(?:, Version: ([\d.]+))?`, "Microsoft ESMTP", "$1"),
+	rule("smtp", "", `^220[ -].*Sendmail ([\w./]+)`, "Sendmail", "$1"),
+	rule("ftp", "", `(?i)^220[ -].*\bftp\b`, "", ""),
+	rule("smtp", "", `(?i)^220[ -].*\bE?SMTP\b`, "", ""),
+	rule("pop3", "", `^\+OK.*Dovecot`, "Dovecot pop3d", ""),
+	rule("pop3", "", `^\+OK`, "", ""),
+	rule("imap", "", `^\* OK.*Dovecot`, "Dovecot imapd", ""),
+	rule("imap", "", `^\* OK.*IMAP`, "", ""),
+	rule("telnet", "", `^\xff[\xfb-\xfe]`, "", ""),
+	rule("vnc", "", `^RFB (\d{3}\.\d{3})`, "VNC", "protocol $1"),
+
+	//MySQL and MariaDB greet the client with a handshake packet
+	rule("mysql", "", `(?s)^.{4}\x0a(?:5\.5\.5-)?([\d.]+)-MariaDB`, "MariaDB", "$1"),
+	rule("mysql", "", `(?s)^.{4}\x0a([\d.]+[\w.\-]*)\x00`, "MySQL", "$1"),
+	rule("mysql", "", `(?s)^.{4}\xff.*is not allowed to connect to this (MySQL|MariaDB) server`, "$1", ""),
+
+	//Active probe responses
+	rule("redis", "", `redis_version:([\d.]+)`, "Redis key-value store", "$1"),
+	rule("redis", "", `^-NOAUTH Authentication required`, "Redis key-value store", ""),
+	rule("redis", "", `^-DENIED Redis`, "Redis key-value store", ""),
+	rule("redis", "generic-lines", `^-ERR unknown command`, "", ""),
+	rule("memcached", "", `^VERSION ([\d.]+)\r\n`, "Memcached", "$1"),
+	rule("postgresql", "postgresql", `^[SN]$`, "PostgreSQL DB", ""),
+	rule("postgresql", "", `(?s)^E.{4}SFATAL`, "PostgreSQL DB", ""),
+	rule("mongodb", "mongodb", `(?s)ismaster.*maxWireVersion`, "MongoDB", ""),
+	rule("ms-wbt-server", "rdp", `^\x03\x00\x00.\x0e\xd0`, "Microsoft Terminal Services", ""),
+	rule("microsoft-ds", "smb", `(?s)^\x00.{3}\xfeSMB`, "Microsoft SMB", "SMB 2+"),
+	rule("microsoft-ds", "smb", `(?s)^\x00.{3}\xffSMBr`, "Microsoft SMB", "SMB 1"),
+	rule("rtsp", "", `^RTSP/1\.0 \d{3}[\s\S]*?\r\nServer: ([^\r\n]+)`, "$1", ""),
+	rule("rtsp", "", `^RTSP/1\.0 \d{3}`, "", ""),
+	rule("sip", "", `^SIP/2\.0 \d{3}[\s\S]*?\r\n(?:Server|User-Agent): ([^\r\n]+)`, "$1", ""),
+	rule("sip", "", `^SIP/2\.0 \d{3}`, "", ""),
+	rule("http", "", `^HTTP/1\.[01] \d{3}[\s\S]*?\r\nServer: ([^/\s\r\n]+)(?:/([\w.\-]+))?`, "$1", "$2"),
+	rule("http", "", `^HTTP/1\.[01] \d{3}`, "", ""),
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/efecankaya/go-port-scanner/data"
+)
+
+// Confidence levels follow nmap's convention of 0 to 10.
+const (
+	ConfidenceTable = 3  //Guessed from the port number only
+	ConfidenceProbe = 8  //Protocol recognized but not the exact product
+	ConfidenceMatch = 10 //Product signature matched
+)
+
+type ServiceInfo struct {
+	Name       string `json:"name"`              //Service name
+	Product    string `json:"product,omitempty"` //Product implementing the service
+	Version    string `json:"version,omitempty"` //Product version
+	Confidence int    `json:"confidence"`        //Confidence of the identification, 0-10
+	Method     string `json:"method"`            //How the service was identified, "table" or "probe"
+}
+
+// Lookup labels a port using the bundled IANA port table.
+func Lookup(port int) (ServiceInfo, bool) {
+	name, ok := data.PortToService[port]
+	if !ok {
+		return ServiceInfo{}, false
+	}
+	return ServiceInfo{Name: name, Confidence: ConfidenceTable, Method: "table"}, true
+}
+
+type matchRule struct {
+	service string         //Service name reported on match
+	probe   string         //Only apply to responses of this probe, empty for any
+	pattern *regexp.Regexp //Signature, matched against the latin-1 decoded response
+	product string         //Product template, may reference submatches
+	version string         //Version template, may reference submatches
+}
+
+// Match identifies a service from a raw response. probe is the name of the
+// probe that elicited the response, empty for a passive banner.
+func Match(response []byte, probe string) (ServiceInfo, bool) {
+	text := latin1(response)
+	for _, rule := range matchRules {
+		if rule.probe != "" && rule.probe != probe {
+			continue
+		}
+		submatches := rule.pattern.FindStringSubmatchIndex(text)
+		if submatches == nil {
+			continue
+		}
+		info := ServiceInfo{Name: rule.service, Confidence: ConfidenceProbe, Method: "probe"}
+		if rule.product != "" {
+			info.Product = string(rule.pattern.ExpandString(nil, rule.product, text, submatches))
+			info.Version = string(rule.pattern.ExpandString(nil, rule.version, text, submatches))
+			info.Confidence = ConfidenceMatch
+		}
+		return info, true
+	}
+	return ServiceInfo{}, false
+}
+
+var serverHeaderPattern = regexp.MustCompile(`^([^/\s]+)(?:/([\w.\-]+))?`)
+
+// FromHTTP identifies a web server from its response headers.
+func FromHTTP(headers map[string]string, tls bool) ServiceInfo {
+	info := ServiceInfo{Name: "http", Confidence: ConfidenceProbe, Method: "probe"}
+	if tls {
+		info.Name = "https"
+	}
+	for key, value := range headers {
+		if !strings.EqualFold(key, "Server") {
+			continue
+		}
+		if m := serverHeaderPattern.FindStringSubmatch(value); m != nil {
+			info.Product, info.Version = m[1], m[2]
+			info.Confidence = ConfidenceMatch
+		}
+	}
+	return info
+}
+
+// latin1 maps every byte to the rune of the same value so binary responses
+// can be matched with regular expressions byte by byte.
+func latin1(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
@@ -0,0 +1,75 @@
+package service
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		response string
+		probe    string
+		want     ServiceInfo
+		ok       bool
+	}{
+		{"SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13\r\n", "", ServiceInfo{Name: "ssh", Product: "OpenSSH", Version: "9.6p1", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"SSH-2.0-dropbear_2022.83\r\n", "", ServiceInfo{Name: "ssh", Product: "Dropbear sshd", Version: "2022.83", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"SSH-2.0-libssh_0.9.6\r\n", "", ServiceInfo{Name: "ssh", Product: "libssh_0.9.6", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"220 (vsFTPd 3.0.5)\r\n", "", ServiceInfo{Name: "ftp", Product: "vsftpd", Version: "3.0.5", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"220 files.example.com FTP server ready\r\n", "", ServiceInfo{Name: "ftp", Confidence: ConfidenceProbe, Method: "probe"}, true},
+		{"220 mx.example.com ESMTP Postfix (Ubuntu)\r\n", "", ServiceInfo{Name: "smtp", Product: "Postfix smtpd", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"220 mx.example.com ESMTP ready\r\n", "", ServiceInfo{Name: "smtp", Confidence: ConfidenceProbe, Method: "probe"}, true},
+		{"* OK [CAPABILITY IMAP4rev1] Dovecot ready.\r\n", "", ServiceInfo{Name: "imap", Product: "Dovecot imapd", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"\xff\xfd\x18\xff\xfd\x20", "", ServiceInfo{Name: "telnet", Confidence: ConfidenceProbe, Method: "probe"}, true},
+		{"J\x00\x00\x00\x0a8.0.36\x00\x08\x00\x00\x00", "", ServiceInfo{Name: "mysql", Product: "MySQL", Version: "8.0.36", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"n\x00\x00\x00\x0a5.5.5-10.11.6-MariaDB-0+deb12u1\x00", "", ServiceInfo{Name: "mysql", Product: "MariaDB", Version: "10.11.6", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"$3000\r\n# Server\r\nredis_version:7.2.4\r\n", "redis", ServiceInfo{Name: "redis", Product: "Redis key-value store", Version: "7.2.4", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"HTTP/1.1 400 Bad Request\r\nServer: nginx/1.24.0\r\n\r\n", "http-get", ServiceInfo{Name: "http", Product: "nginx", Version: "1.24.0", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"HTTP/1.0 200 OK\r\n\r\n", "http-get", ServiceInfo{Name: "http", Confidence: ConfidenceProbe, Method: "probe"}, true},
+
+		//Rules bound to a probe only match its responses
+		{"-ERR unknown command 'HELP'\r\n", "generic-lines", ServiceInfo{Name: "redis", Confidence: ConfidenceProbe, Method: "probe"}, true},
+		{"-ERR unknown command 'HELP'\r\n", "", ServiceInfo{}, false},
+		{"S", "postgresql", ServiceInfo{Name: "postgresql", Product: "PostgreSQL DB", Confidence: ConfidenceMatch, Method: "probe"}, true},
+		{"S", "", ServiceInfo{}, false},
+
+		{"", "", ServiceInfo{}, false},
+		{"hello\r\n", "", ServiceInfo{}, false},
+	}
+	for _, test := range tests {
+		got, ok := Match([]byte(test.response), test.probe)
+		if ok != test.ok || got != test.want {
+			t.Errorf("Match(%q, %q) = %+v, %v, want %+v, %v", test.response, test.probe, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestFromHTTP(t *testing.T) {
+	tests := []struct {
+		headers map[string]string
+		tls     bool
+		want    ServiceInfo
+	}{
+		{map[string]string{"Server": "Apache/2.4.58 (Ubuntu)"}, false, ServiceInfo{Name: "http", Product: "Apache", Version: "2.4.58", Confidence: ConfidenceMatch, Method: "probe"}},
+		{map[string]string{"server": "cloudflare"}, true, ServiceInfo{Name: "https", Product: "cloudflare", Confidence: ConfidenceMatch, Method: "probe"}},
+		{map[string]string{"Content-Type": "text/html"}, false, ServiceInfo{Name: "http", Confidence: ConfidenceProbe, Method: "probe"}},
+		{nil, true, ServiceInfo{Name: "https", Confidence: ConfidenceProbe, Method: "probe"}},
+	}
+	for _, test := range tests {
+		if got := FromHTTP(test.headers, test.tls); got != test.want {
+			t.Errorf("FromHTTP(%v, %v) = %+v, want %+v", test.headers, test.tls, got, test.want)
+		}
+	}
+}
+
+func TestLookup(t *testing.T) {
+	if got, ok := Lookup(22); !ok || got != (ServiceInfo{Name: "ssh", Confidence: ConfidenceTable, Method: "table"}) {
+		t.Errorf("Lookup(22) = %+v, %v", got, ok)
+	}
+	if _, ok := Lookup(0); ok {
+		t.Error("Lookup(0) found a service")
+	}
+}
+
+func TestLatin1(t *testing.T) {
+	if got := latin1([]byte{'a', 0x00, 0xff}); got != "a\x00ÿ" {
+		t.Errorf("latin1 = %q", got)
+	}
+}
@@ -0,0 +1,57 @@
+// Package synscan sends raw TCP SYN packets and classifies ports from the
+// replies without ever completing a handshake. Bare ACK probes tell whether a
+// host answers at all. Opening the raw socket needs
+// root or CAP_NET_RAW and is only supported on Linux for IPv4 targets.
+package synscan
+
+import (
+	"errors"
+	"net"
+)
+
+const (
+	StateOpen       = "open"       //SYN-ACK received
+	StateClosed     = "closed"     //RST received
+	StateFiltered   = "filtered"   //No reply
+	StateUnfiltered = "unfiltered" //RST received for an ACK
+)
+
+var (
+	ErrUnsupported = errors.New("syn scan is not supported on this platform")
+	ErrIPv6        = errors.New("syn scan supports IPv4 targets only")
+)
+
+// Reply describes the answer to a SYN or ACK.
+type Reply struct {
+	State  string //One of the state constants
+	TTL    int    //IP time to live of the reply
+	Window int    //TCP window size of the reply
+}
+
+type replyKey struct {
+	ip       [4]byte //Address of the target
+	port     uint16  //Port of the target
+	src_port uint16  //Local port the SYN was sent from
+}
+
+// checksum computes the internet checksum of the TCP segment including the
+// IPv4 pseudo header.
+func checksum(src, dst net.IP, segment []byte) uint16 {
+	var sum uint32
+	add := func(data []byte) {
+		for i := 0; i+1 < len(data); i += 2 {
+			sum += uint32(data[i])<<8 | uint32(data[i+1])
+		}
+		if len(data)%2 == 1 {
+			sum += uint32(data[len(data)-1]) << 8
+		}
+	}
+	add(src.To4())
+	add(dst.To4())
+	add([]byte{0, 6, byte(len(segment) >> 8), byte(len(segment))})
+	add(segment)
+	for sum > 0xffff {
+		sum = sum>>16 + sum&0xffff
+	}
+	return ^uint16(sum)
+}
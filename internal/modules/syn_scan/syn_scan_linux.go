@@ -0,0 +1,228 @@
+//go:build linux
+
+package synscan
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"net"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Scanner owns the raw socket shared by all probes.
+type Scanner struct {
+	fd       int
+	closed   atomic.Bool
+	done     chan struct{} //Closed once the receive loop released the socket
+	next     atomic.Uint32 //Counter for local ports
+	mu       sync.Mutex
+	waiters  map[replyKey]chan Reply
+	sources  map[[4]byte]net.IP //Local address used towards each target
+	sourceMu sync.Mutex
+}
+
+// New opens the raw socket. It fails with an error wrapping syscall.EPERM
+// when the process lacks the privileges.
+func New() (*Scanner, error) {
+	fd, err := syscall.Socket(syscall.AF_INET, syscall.SOCK_RAW, syscall.IPPROTO_TCP)
+	if err != nil {
+		return nil, &net.OpError{Op: "socket", Net: "ip4:tcp", Err: err}
+	}
+	//Wake the receive loop regularly so Close is noticed
+	timeout := syscall.NsecToTimeval(int64(200 * time.Millisecond))
+	if err := syscall.SetsockoptTimeval(fd, syscall.SOL_SOCKET, syscall.SO_RCVTIMEO, &timeout); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	s := &Scanner{
+		fd:      fd,
+		done:    make(chan struct{}),
+		waiters: make(map[replyKey]chan Reply),
+		sources: make(map[[4]byte]net.IP),
+	}
+	s.next.Store(uint32(rand.Intn(16384)))
+	go s.receive()
+	return s, nil
+}
+
+// Close stops the receive loop and releases the raw socket.
+func (s *Scanner) Close() error {
+	s.closed.Store(true)
+	<-s.done
+	return nil
+}
+
+// Probe sends a SYN to ip:port, resending it once, and waits up to timeout
+// for each reply.
+func (s *Scanner) Probe(ctx context.Context, ip net.IP, port int, timeout time.Duration) (Reply, error) {
+	return s.send(ctx, ip, port, timeout, synSegment)
+}
+
+// ProbeACK sends a bare ACK to ip:port, resending it once, and waits up to
+// timeout for each reply. Hosts answer an unexpected ACK with a RST whether the
+// port is open or closed, so the reply is StateUnfiltered or StateFiltered.
+func (s *Scanner) ProbeACK(ctx context.Context, ip net.IP, port int, timeout time.Duration) (Reply, error) {
+	reply, err := s.send(ctx, ip, port, timeout, ackSegment)
+	if reply.State == StateClosed {
+		reply.State = StateUnfiltered
+	}
+	return reply, err
+}
+
+// send transmits the segment built by build to ip:port and waits for the reply.
+func (s *Scanner) send(ctx context.Context, ip net.IP, port int, timeout time.Duration, build func(src, dst net.IP, src_port, dst_port uint16) []byte) (Reply, error) {
+	dst := ip.To4()
+	if dst == nil {
+		return Reply{}, ErrIPv6
+	}
+	src, err := s.source(dst)
+	if err != nil {
+		return Reply{}, err
+	}
+	key := replyKey{port: uint16(port), src_port: uint16(40000 + s.next.Add(1)%20000)}
+	copy(key.ip[:], dst)
+	replies := make(chan Reply, 1)
+	s.mu.Lock()
+	s.waiters[key] = replies
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.waiters, key)
+		s.mu.Unlock()
+	}()
+
+	segment := build(src, dst, key.src_port, key.port)
+	addr := &syscall.SockaddrInet4{}
+	copy(addr.Addr[:], dst)
+	for attempt := 0; attempt < 2; attempt++ {
+		if err := syscall.Sendto(s.fd, segment, 0, addr); err != nil {
+			return Reply{}, err
+		}
+		timer := time.NewTimer(timeout)
+		select {
+		case reply := <-replies:
+			timer.Stop()
+			return reply, nil
+		case <-ctx.Done():
+			timer.Stop()
+			return Reply{}, ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return Reply{State: StateFiltered}, nil
+}
+
+// source finds the local address the kernel routes towards dst through.
+func (s *Scanner) source(dst net.IP) (net.IP, error) {
+	var key [4]byte
+	copy(key[:], dst)
+	s.sourceMu.Lock()
+	defer s.sourceMu.Unlock()
+	if src, ok := s.sources[key]; ok {
+		return src, nil
+	}
+	conn, err := net.DialUDP("udp4", nil, &net.UDPAddr{IP: dst, Port: 9})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	src := conn.LocalAddr().(*net.UDPAddr).IP.To4()
+	s.sources[key] = src
+	return src, nil
+}
+
+// receive dispatches incoming SYN-ACK and RST segments to waiting probes.
+func (s *Scanner) receive() {
+	defer close(s.done)
+	defer syscall.Close(s.fd)
+	buf := make([]byte, 1500)
+	for !s.closed.Load() {
+		n, _, err := syscall.Recvfrom(s.fd, buf, 0)
+		if err != nil {
+			if errors.Is(err, syscall.EAGAIN) || errors.Is(err, syscall.EINTR) {
+				continue
+			}
+			return
+		}
+		packet := buf[:n]
+		if len(packet) < 20 || packet[0]>>4 != 4 {
+			continue
+		}
+		ihl := int(packet[0]&0x0f) * 4
+		if len(packet) < ihl+20 {
+			continue
+		}
+		tcp := packet[ihl:]
+		flags := tcp[13]
+		var state string
+		switch {
+		case flags&(flagSYN|flagACK) == flagSYN|flagACK:
+			state = StateOpen
+		case flags&flagRST != 0:
+			state = StateClosed
+		default:
+			continue
+		}
+		var key replyKey
+		copy(key.ip[:], packet[12:16])
+		key.port = binary.BigEndian.Uint16(tcp[0:2])
+		key.src_port = binary.BigEndian.Uint16(tcp[2:4])
+
+		s.mu.Lock()
+		replies, ok := s.waiters[key]
+		s.mu.Unlock()
+		if !ok {
+			continue
+		}
+		select {
+		case replies <- Reply{State: state, TTL: int(packet[8]), Window: int(binary.BigEndian.Uint16(tcp[14:16]))}:
+		default:
+		}
+	}
+}
+
+const (
+	flagRST = 0x04
+	flagSYN = 0x02
+	flagACK = 0x10
+)
+
+// synSegment builds a TCP SYN carrying an MSS option.
+func synSegment(src, dst net.IP, src_port, dst_port uint16) []byte {
+	return tcpSegment(src, dst, src_port, dst_port, flagSYN)
+}
+
+// ackSegment builds a TCP ACK that belongs to no connection.
+func ackSegment(src, dst net.IP, src_port, dst_port uint16) []byte {
+	return tcpSegment(src, dst, src_port, dst_port, flagACK)
+}
+
+// tcpSegment builds a TCP segment with the given flags and random sequence
+// and acknowledgment numbers. A SYN carries an MSS option like the ones the
+// kernel sends.
+func tcpSegment(src, dst net.IP, src_port, dst_port uint16, flags byte) []byte {
+	size := 20
+	if flags&flagSYN != 0 {
+		size += 4 //MSS option
+	}
+	segment := make([]byte, size)
+	binary.BigEndian.PutUint16(segment[0:], src_port)
+	binary.BigEndian.PutUint16(segment[2:], dst_port)
+	binary.BigEndian.PutUint32(segment[4:], rand.Uint32()) //Sequence number
+	if flags&flagACK != 0 {
+		binary.BigEndian.PutUint32(segment[8:], rand.Uint32()) //Acknowledgment number
+	}
+	segment[12] = byte(size/4) << 4                //Data offset in 32-bit words
+	segment[13] = flags                            //Control bits
+	binary.BigEndian.PutUint16(segment[14:], 1024) //Window
+	if flags&flagSYN != 0 {
+		copy(segment[20:], []byte{0x02, 0x04, 0x05, 0xb4}) //MSS 1460
+	}
+	binary.BigEndian.PutUint16(segment[16:], checksum(src, dst, segment))
+	return segment
+}
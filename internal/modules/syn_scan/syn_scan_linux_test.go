@@ -0,0 +1,126 @@
+//go:build linux
+
+package synscan
+
+import (
+	"context"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestSynSegment(t *testing.T) {
+	tests := []struct {
+		src, dst           string
+		src_port, dst_port uint16
+	}{
+		{"10.0.0.1", "10.0.0.2", 40001, 80},
+		{"192.0.2.1", "198.51.100.7", 59999, 443},
+		{"127.0.0.1", "127.0.0.1", 40000, 65535},
+	}
+	for _, test := range tests {
+		src, dst := net.ParseIP(test.src), net.ParseIP(test.dst)
+		segment := synSegment(src, dst, test.src_port, test.dst_port)
+		if len(segment) != 24 {
+			t.Fatalf("segment length = %d, want 24", len(segment))
+		}
+		if got := binary.BigEndian.Uint16(segment[0:]); got != test.src_port {
+			t.Errorf("source port = %d, want %d", got, test.src_port)
+		}
+		if got := binary.BigEndian.Uint16(segment[2:]); got != test.dst_port {
+			t.Errorf("destination port = %d, want %d", got, test.dst_port)
+		}
+		if got := binary.BigEndian.Uint32(segment[8:]); got != 0 {
+			t.Errorf("acknowledgment number = %d, want 0", got)
+		}
+		if got := int(segment[12]>>4) * 4; got != len(segment) {
+			t.Errorf("data offset = %d bytes, want %d", got, len(segment))
+		}
+		if segment[13] != 0x02 {
+			t.Errorf("flags = %#02x, want SYN only", segment[13])
+		}
+		if got := binary.BigEndian.Uint16(segment[14:]); got != 1024 {
+			t.Errorf("window = %d, want 1024", got)
+		}
+		if got := binary.BigEndian.Uint16(segment[18:]); got != 0 {
+			t.Errorf("urgent pointer = %d, want 0", got)
+		}
+		if got, want := segment[20:], []byte{0x02, 0x04, 0x05, 0xb4}; string(got) != string(want) {
+			t.Errorf("options = % x, want % x", got, want)
+		}
+		if got := checksum(src, dst, segment); got != 0 {
+			t.Errorf("segment %s:%d -> %s:%d does not verify, checksum over it = %#04x", test.src, test.src_port, test.dst, test.dst_port, got)
+		}
+	}
+}
+
+func TestACKSegment(t *testing.T) {
+	src, dst := net.ParseIP("192.0.2.1"), net.ParseIP("198.51.100.7")
+	segment := ackSegment(src, dst, 40001, 80)
+	if len(segment) != 20 {
+		t.Fatalf("segment length = %d, want 20 without options", len(segment))
+	}
+	if got := int(segment[12]>>4) * 4; got != len(segment) {
+		t.Errorf("data offset = %d bytes, want %d", got, len(segment))
+	}
+	if segment[13] != 0x10 {
+		t.Errorf("flags = %#02x, want ACK only", segment[13])
+	}
+	if got := checksum(src, dst, segment); got != 0 {
+		t.Errorf("segment does not verify, checksum over it = %#04x", got)
+	}
+}
+
+func TestProbeLoopback(t *testing.T) {
+	s, err := New()
+	if err != nil {
+		t.Skipf("raw socket unavailable, needs CAP_NET_RAW: %v", err)
+	}
+	defer s.Close()
+
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	open_port := listener.Addr().(*net.TCPAddr).Port
+	closed, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed_port := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	tests := []struct {
+		port int
+		want string
+	}{
+		{open_port, StateOpen},
+		{closed_port, StateClosed},
+	}
+	for _, test := range tests {
+		reply, err := s.Probe(context.Background(), net.IPv4(127, 0, 0, 1), test.port, time.Second)
+		if err != nil {
+			t.Errorf("Probe(%d) error: %v", test.port, err)
+			continue
+		}
+		if reply.State != test.want {
+			t.Errorf("Probe(%d) state = %q, want %q", test.port, reply.State, test.want)
+		}
+		if reply.TTL == 0 {
+			t.Errorf("Probe(%d) reply has no TTL", test.port)
+		}
+	}
+
+	for _, port := range []int{open_port, closed_port} {
+		reply, err := s.ProbeACK(context.Background(), net.IPv4(127, 0, 0, 1), port, time.Second)
+		if err != nil || reply.State != StateUnfiltered {
+			t.Errorf("ProbeACK(%d) = %q, %v, want %q", port, reply.State, err, StateUnfiltered)
+		}
+	}
+
+	if _, err := s.Probe(context.Background(), net.ParseIP("::1"), open_port, time.Second); err != ErrIPv6 {
+		t.Errorf("Probe(::1) error = %v, want ErrIPv6", err)
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+
+package synscan
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// Scanner is not available on this platform.
+type Scanner struct{}
+
+// New always fails with ErrUnsupported on this platform.
+func New() (*Scanner, error) {
+	return nil, ErrUnsupported
+}
+
+func (s *Scanner) Close() error {
+	return nil
+}
+
+func (s *Scanner) Probe(ctx context.Context, ip net.IP, port int, timeout time.Duration) (Reply, error) {
+	return Reply{}, ErrUnsupported
+}
+
+func (s *Scanner) ProbeACK(ctx context.Context, ip net.IP, port int, timeout time.Duration) (Reply, error) {
+	return Reply{}, ErrUnsupported
+}
@@ -0,0 +1,36 @@
+package synscan
+
+import (
+	"net"
+	"testing"
+)
+
+func TestChecksum(t *testing.T) {
+	tests := []struct {
+		name     string
+		src, dst string
+		segment  []byte
+		want     uint16
+	}{
+		{name: "empty header", src: "10.0.0.1", dst: "10.0.0.2", segment: make([]byte, 20), want: 0xebe2},
+		{name: "odd length", src: "10.0.0.1", dst: "10.0.0.2", segment: []byte{0x01}, want: 0xeaf5},
+		{name: "carry folded", src: "255.255.255.255", dst: "255.255.255.255", segment: []byte{0xff, 0xff, 0xff, 0xff}, want: 0xfff5},
+		{name: "IPv4 mapped", src: "::ffff:10.0.0.1", dst: "::ffff:10.0.0.2", segment: make([]byte, 20), want: 0xebe2},
+	}
+	for _, test := range tests {
+		got := checksum(net.ParseIP(test.src), net.ParseIP(test.dst), test.segment)
+		if got != test.want {
+			t.Errorf("%s: checksum = %#04x, want %#04x", test.name, got, test.want)
+		}
+	}
+}
+
+func TestChecksumVerifies(t *testing.T) {
+	src, dst := net.ParseIP("192.0.2.1"), net.ParseIP("198.51.100.7")
+	segment := []byte{0x9c, 0x40, 0x00, 0x50, 0x12, 0x34, 0x56, 0x78, 0, 0, 0, 0, 0x50, 0x02, 0x04, 0x00, 0, 0, 0, 0, 0xab}
+	sum := checksum(src, dst, segment)
+	segment[16], segment[17] = byte(sum>>8), byte(sum)
+	if got := checksum(src, dst, segment); got != 0 {
+		t.Errorf("checksum over a segment carrying its checksum = %#04x, want 0", got)
+	}
+}
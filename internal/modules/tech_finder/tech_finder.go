@@ -1,18 +1,412 @@
+// Package techfinder identifies the technologies behind an HTTP response by
+// matching its headers, cookies, HTML, script sources, meta tags and favicon
+// hash against signature rules in the format of Wappalyzer. A rules file is
+// bundled, an updated one can be loaded instead.
 package techfinder
 
 import (
+	_ "embed"
+	"encoding/json"
 	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
 
-	"github.com/efecankaya/go-port-scanner/internal/utils"
+	"golang.org/x/net/html"
 )
 
-func HttpAnalyze(http_response_body string, http_response_header string) []string {
-	//Analyze http/https response
-	var tags = []string{"link", "script", "meta"}
-	html_tag_extract, err := utils.ExtractTags(http_response_body, tags)
+//go:embed rules.json
+var bundledRules []byte
+
+// Technology is a technology found in a response.
+type Technology struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version,omitempty"`
+	Categories []string `json:"categories,omitempty"`
+	Confidence int      `json:"confidence"` //0-100
+}
+
+// Response is what a technology is detected from.
+type Response struct {
+	Headers  map[string]string //Response headers
+	Cookies  map[string]string //Cookies set by the response
+	Body     string            //HTML body
+	Favicons []int32           //Shodan style MMH3 hashes of the favicons
+}
+
+// Engine matches responses against a set of rules. It is safe for concurrent
+// use.
+type Engine struct {
+	technologies []technology
+	byName       map[string]*technology
+	skipped      []string //Patterns left out because they do not compile
+}
+
+type technology struct {
+	name       string
+	categories []string
+	headers    map[string][]pattern //Lower case header name to patterns
+	cookies    map[string][]pattern //Lower case cookie name to patterns
+	meta       map[string][]pattern //Lower case meta name or property to patterns
+	html       []pattern
+	scriptSrc  []pattern
+	favicons   map[int32]bool
+	implies    []string
+}
+
+// pattern is a Wappalyzer pattern: a regular expression followed by \;
+// separated tags, version:\1 and confidence:50 are understood.
+type pattern struct {
+	regex      *regexp.Regexp //Nil matches anything, the field only needs to exist
+	version    string         //Template with \N group references
+	confidence int
+}
+
+// rulesFile is the layout of a rules file. Most fields accept a string or a
+// list of strings. Categories are names or, as in Wappalyzer, numeric IDs
+// looked up in the categories object.
+type rulesFile struct {
+	Categories map[string]struct {
+		Name string `json:"name"`
+	} `json:"categories"`
+	Technologies map[string]struct {
+		Cats      []json.RawMessage          `json:"cats"`
+		Headers   map[string]json.RawMessage `json:"headers"`
+		Cookies   map[string]json.RawMessage `json:"cookies"`
+		Meta      map[string]json.RawMessage `json:"meta"`
+		HTML      json.RawMessage            `json:"html"`
+		ScriptSrc json.RawMessage            `json:"scriptSrc"`
+		Favicon   []int32                    `json:"favicon"`
+		Implies   json.RawMessage            `json:"implies"`
+	} `json:"technologies"`
+}
+
+// Default returns an Engine using the bundled rules.
+func Default() *Engine {
+	engine, err := New(bundledRules)
 	if err != nil {
-		fmt.Println("Error: ", err)
+		panic("techfinder: bundled rules: " + err.Error())
+	}
+	return engine
+}
+
+// Load reads a rules file from path.
+func Load(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(data)
+}
+
+// New parses rules in the JSON format of the bundled rules file. Patterns
+// that Go's regular expressions cannot compile, such as the lookarounds found
+// in Wappalyzer rules, are left out and listed by Skipped.
+func New(data []byte) (*Engine, error) {
+	var file rulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	engine := &Engine{byName: make(map[string]*technology)}
+	for name, rules := range file.Technologies {
+		tech := technology{name: name, favicons: make(map[int32]bool)}
+		for _, raw := range rules.Cats {
+			category, err := categoryName(raw, file.Categories)
+			if err != nil {
+				return nil, fmt.Errorf("%s cats: %w", name, err)
+			}
+			tech.categories = append(tech.categories, category)
+		}
+		skip := func(field string) func(error) {
+			return func(err error) {
+				engine.skipped = append(engine.skipped, fmt.Sprintf("%s %s: %v", name, field, err))
+			}
+		}
+		var err error
+		if tech.headers, err = parsePatternMap(rules.Headers, skip("headers")); err != nil {
+			return nil, fmt.Errorf("%s headers: %w", name, err)
+		}
+		if tech.cookies, err = parsePatternMap(rules.Cookies, skip("cookies")); err != nil {
+			return nil, fmt.Errorf("%s cookies: %w", name, err)
+		}
+		if tech.meta, err = parsePatternMap(rules.Meta, skip("meta")); err != nil {
+			return nil, fmt.Errorf("%s meta: %w", name, err)
+		}
+		if tech.html, err = parsePatternList(rules.HTML, skip("html")); err != nil {
+			return nil, fmt.Errorf("%s html: %w", name, err)
+		}
+		if tech.scriptSrc, err = parsePatternList(rules.ScriptSrc, skip("scriptSrc")); err != nil {
+			return nil, fmt.Errorf("%s scriptSrc: %w", name, err)
+		}
+		if tech.implies, err = stringList(rules.Implies); err != nil {
+			return nil, fmt.Errorf("%s implies: %w", name, err)
+		}
+		for i, implied := range tech.implies { //Implied technologies may carry tags too
+			tech.implies[i], _, _ = strings.Cut(implied, `\;`)
+		}
+		for _, hash := range rules.Favicon {
+			tech.favicons[hash] = true
+		}
+		engine.technologies = append(engine.technologies, tech)
+	}
+	sort.Slice(engine.technologies, func(i, j int) bool { return engine.technologies[i].name < engine.technologies[j].name })
+	for i := range engine.technologies {
+		engine.byName[engine.technologies[i].name] = &engine.technologies[i]
+	}
+	sort.Strings(engine.skipped)
+	return engine, nil
+}
+
+// Skipped lists the patterns left out of the rules because they do not
+// compile, with the technology and field they belong to.
+func (e *Engine) Skipped() []string {
+	return e.skipped
+}
+
+// categoryName returns the category named by raw, either directly or through
+// its numeric ID. IDs missing from categories are kept as numbers.
+func categoryName(raw json.RawMessage, categories map[string]struct {
+	Name string `json:"name"`
+}) (string, error) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return name, nil
 	}
+	var id int
+	if err := json.Unmarshal(raw, &id); err != nil {
+		return "", err
+	}
+	if category, ok := categories[strconv.Itoa(id)]; ok && category.Name != "" {
+		return category.Name, nil
+	}
+	return strconv.Itoa(id), nil
+}
 
-	return html_tag_extract
+func stringList(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list, nil
+	}
+	var single string
+	if err := json.Unmarshal(raw, &single); err != nil {
+		return nil, err
+	}
+	return []string{single}, nil
+}
+
+// parsePatternList parses a pattern or list of patterns, passing the ones
+// that do not compile to skip.
+func parsePatternList(raw json.RawMessage, skip func(error)) ([]pattern, error) {
+	list, err := stringList(raw)
+	if err != nil {
+		return nil, err
+	}
+	patterns := make([]pattern, 0, len(list))
+	for _, value := range list {
+		p, err := parsePattern(value)
+		if err != nil {
+			skip(err)
+			continue
+		}
+		patterns = append(patterns, p)
+	}
+	return patterns, nil
+}
+
+func parsePatternMap(raw map[string]json.RawMessage, skip func(error)) (map[string][]pattern, error) {
+	patterns := make(map[string][]pattern, len(raw))
+	for key, value := range raw {
+		list, err := parsePatternList(value, func(err error) { skip(fmt.Errorf("%s: %w", key, err)) })
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		patterns[strings.ToLower(key)] = list
+	}
+	return patterns, nil
+}
+
+func parsePattern(value string) (pattern, error) {
+	parts := strings.Split(value, `\;`)
+	p := pattern{confidence: 100}
+	if parts[0] != "" {
+		regex, err := regexp.Compile("(?i)" + parts[0])
+		if err != nil {
+			return p, err
+		}
+		p.regex = regex
+	}
+	for _, tag := range parts[1:] {
+		key, value, _ := strings.Cut(tag, ":")
+		switch key {
+		case "version":
+			p.version = value
+		case "confidence":
+			if confidence, err := strconv.Atoi(value); err == nil {
+				p.confidence = confidence
+			}
+		}
+	}
+	return p, nil
+}
+
+// match reports whether p matches value and the version it extracts.
+func (p pattern) match(value string) (bool, string) {
+	if p.regex == nil {
+		return true, ""
+	}
+	groups := p.regex.FindStringSubmatch(value)
+	if groups == nil {
+		return false, ""
+	}
+	if p.version == "" {
+		return true, ""
+	}
+	version := p.version
+	for i := len(groups) - 1; i >= 1; i-- { //Replace \10 before \1
+		version = strings.ReplaceAll(version, `\`+strconv.Itoa(i), groups[i])
+	}
+	return true, strings.TrimSpace(version)
+}
+
+// document holds the parts of an HTML body rules look at.
+type document struct {
+	scripts []string            //src attributes of script tags
+	meta    map[string][]string //Lower case name or property to content
+}
+
+func parseDocument(body string) document {
+	doc := document{meta: make(map[string][]string)}
+	tokenizer := html.NewTokenizer(strings.NewReader(body))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return doc
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			attr := func(name string) string {
+				for _, a := range token.Attr {
+					if a.Key == name {
+						return a.Val
+					}
+				}
+				return ""
+			}
+			switch token.Data {
+			case "script":
+				if src := attr("src"); src != "" {
+					doc.scripts = append(doc.scripts, src)
+				}
+			case "meta":
+				name := attr("name")
+				if name == "" {
+					name = attr("property")
+				}
+				if name != "" {
+					doc.meta[strings.ToLower(name)] = append(doc.meta[strings.ToLower(name)], attr("content"))
+				}
+			}
+		}
+	}
+}
+
+// Analyze returns the technologies found in response, including the ones
+// implied by them, sorted by name.
+func (e *Engine) Analyze(response Response) []Technology {
+	headers := lowerKeys(response.Headers)
+	cookies := lowerKeys(response.Cookies)
+	doc := parseDocument(response.Body)
+
+	found := make(map[string]*Technology)
+	detect := func(tech *technology, matched bool, version string, confidence int) {
+		if !matched {
+			return
+		}
+		result, ok := found[tech.name]
+		if !ok {
+			result = &Technology{Name: tech.name, Categories: tech.categories}
+			found[tech.name] = result
+		}
+		result.Confidence = min(100, result.Confidence+confidence)
+		if len(version) > len(result.Version) { //Prefer the most precise version
+			result.Version = version
+		}
+	}
+	matchAll := func(tech *technology, patterns []pattern, values ...string) {
+		for _, p := range patterns {
+			for _, value := range values {
+				matched, version := p.match(value)
+				detect(tech, matched, version, p.confidence)
+				if matched {
+					break
+				}
+			}
+		}
+	}
+
+	for i := range e.technologies {
+		tech := &e.technologies[i]
+		for name, patterns := range tech.headers {
+			if value, ok := headers[name]; ok {
+				matchAll(tech, patterns, value)
+			}
+		}
+		for name, patterns := range tech.cookies {
+			if value, ok := cookies[name]; ok {
+				matchAll(tech, patterns, value)
+			}
+		}
+		for name, patterns := range tech.meta {
+			if values, ok := doc.meta[name]; ok {
+				matchAll(tech, patterns, values...)
+			}
+		}
+		if response.Body != "" {
+			matchAll(tech, tech.html, response.Body)
+		}
+		matchAll(tech, tech.scriptSrc, doc.scripts...)
+		for _, hash := range response.Favicons {
+			detect(tech, tech.favicons[hash], "", 100)
+		}
+	}
+
+	var queue []string
+	for name := range found {
+		queue = append(queue, name)
+	}
+	for len(queue) > 0 { //Add implied technologies, following chains
+		tech := e.byName[queue[0]]
+		queue = queue[1:]
+		if tech == nil {
+			continue
+		}
+		for _, implied := range tech.implies {
+			if _, ok := found[implied]; ok {
+				continue
+			}
+			if implied_tech := e.byName[implied]; implied_tech != nil {
+				found[implied] = &Technology{Name: implied, Categories: implied_tech.categories, Confidence: found[tech.name].Confidence}
+				queue = append(queue, implied)
+			}
+		}
+	}
+
+	technologies := make([]Technology, 0, len(found))
+	for _, tech := range found {
+		technologies = append(technologies, *tech)
+	}
+	sort.Slice(technologies, func(i, j int) bool { return technologies[i].Name < technologies[j].Name })
+	return technologies
+}
+
+func lowerKeys(values map[string]string) map[string]string {
+	lower := make(map[string]string, len(values))
+	for key, value := range values {
+		lower[strings.ToLower(key)] = value
+	}
+	return lower
 }
@@ -0,0 +1,160 @@
+package techfinder
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testRules = `{
+  "categories": {"1": {"name": "CMS"}, "22": {"name": "Web servers"}},
+  "technologies": {
+    "Nginx": {"cats": [22], "headers": {"Server": "nginx(?:/([\\d.]+))?\\;version:\\1"}},
+    "OpenResty": {"cats": ["Web servers"], "headers": {"Server": "openresty(?:/([\\d.]+))?\\;version:\\1"}, "implies": "Nginx"},
+    "PHP": {"cats": [99], "cookies": {"PHPSESSID": ""}},
+    "WordPress": {
+      "cats": [1, "Blogs"],
+      "meta": {"generator": "^WordPress ?([\\d.]+)?\\;version:\\1"},
+      "implies": ["PHP\\;confidence:50"]
+    },
+    "WooCommerce": {"cats": ["Ecommerce"], "scriptSrc": ["/woocommerce(?:\\.min)?\\.js"], "implies": ["WordPress"]},
+    "jQuery": {"cats": ["JavaScript libraries"], "scriptSrc": ["jquery[.-]([\\d.]*\\d)[^/]*\\.js\\;version:\\1"]},
+    "Spring": {"cats": ["Web frameworks"], "favicon": [116323821]},
+    "Guess": {"cats": ["Miscellaneous"], "html": ["<div id=\"guess\"\\;confidence:40", "<span id=\"guess\"\\;confidence:40"]},
+    "Lookaround": {"cats": ["Miscellaneous"], "html": ["(?<!foo)bar", "<p id=\"around\">"], "headers": {"X-Around": "(?=x)"}}
+  }
+}`
+
+func testEngine(t *testing.T) *Engine {
+	t.Helper()
+	engine, err := New([]byte(testRules))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func TestAnalyze(t *testing.T) {
+	engine := testEngine(t)
+	tests := []struct {
+		name     string
+		response Response
+		want     []Technology
+	}{
+		{
+			name:     "header with version",
+			response: Response{Headers: map[string]string{"server": "nginx/1.25.3"}},
+			want:     []Technology{{Name: "Nginx", Version: "1.25.3", Categories: []string{"Web servers"}, Confidence: 100}},
+		},
+		{
+			name:     "header without version",
+			response: Response{Headers: map[string]string{"Server": "nginx"}},
+			want:     []Technology{{Name: "Nginx", Categories: []string{"Web servers"}, Confidence: 100}},
+		},
+		{
+			name:     "cookie presence",
+			response: Response{Cookies: map[string]string{"phpsessid": "abc"}},
+			want:     []Technology{{Name: "PHP", Categories: []string{"99"}, Confidence: 100}},
+		},
+		{
+			name:     "implies followed",
+			response: Response{Headers: map[string]string{"Server": "openresty/1.21.4.1"}},
+			want: []Technology{
+				{Name: "Nginx", Categories: []string{"Web servers"}, Confidence: 100},
+				{Name: "OpenResty", Version: "1.21.4.1", Categories: []string{"Web servers"}, Confidence: 100},
+			},
+		},
+		{
+			name:     "meta generator and implies chain",
+			response: Response{Body: `<html><head><meta name="Generator" content="WordPress 6.4.2"><script src="/wp-content/plugins/woocommerce/assets/js/woocommerce.min.js"></script></head></html>`},
+			want: []Technology{
+				{Name: "PHP", Categories: []string{"99"}, Confidence: 100},
+				{Name: "WooCommerce", Categories: []string{"Ecommerce"}, Confidence: 100},
+				{Name: "WordPress", Version: "6.4.2", Categories: []string{"CMS", "Blogs"}, Confidence: 100},
+			},
+		},
+		{
+			name:     "script src version",
+			response: Response{Body: `<script src="https://code.jquery.com/jquery-3.7.1.min.js"></script><script>var jquery = "jquery-9.9.js"</script>`},
+			want:     []Technology{{Name: "jQuery", Version: "3.7.1", Categories: []string{"JavaScript libraries"}, Confidence: 100}},
+		},
+		{
+			name:     "favicon hash",
+			response: Response{Favicons: []int32{1, 116323821}},
+			want:     []Technology{{Name: "Spring", Categories: []string{"Web frameworks"}, Confidence: 100}},
+		},
+		{
+			name:     "partial confidence",
+			response: Response{Body: `<div id="guess"></div>`},
+			want:     []Technology{{Name: "Guess", Categories: []string{"Miscellaneous"}, Confidence: 40}},
+		},
+		{
+			name:     "confidence adds up",
+			response: Response{Body: `<div id="guess"></div><span id="guess"></span>`},
+			want:     []Technology{{Name: "Guess", Categories: []string{"Miscellaneous"}, Confidence: 80}},
+		},
+		{
+			name:     "compiling patterns of a technology with skipped ones",
+			response: Response{Body: `<p id="around">bar</p>`, Headers: map[string]string{"X-Around": "x"}},
+			want:     []Technology{{Name: "Lookaround", Categories: []string{"Miscellaneous"}, Confidence: 100}},
+		},
+		{
+			name:     "nothing",
+			response: Response{Headers: map[string]string{"Server": "Apache"}, Body: "<html></html>"},
+			want:     []Technology{},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := engine.Analyze(test.response); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Analyze = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSkipped(t *testing.T) {
+	skipped := testEngine(t).Skipped()
+	if len(skipped) != 2 {
+		t.Fatalf("Skipped() = %q, want the two lookaround patterns", skipped)
+	}
+	if !strings.HasPrefix(skipped[0], "Lookaround headers: X-Around: ") || !strings.HasPrefix(skipped[1], "Lookaround html: ") {
+		t.Errorf("Skipped() = %q", skipped)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	for _, rules := range []string{
+		`{"technologies": {"A": {"cats": [{"id": 1}]}}}`,
+		`{"technologies": {"A": {"html": 5}}}`,
+		`not json`,
+	} {
+		if _, err := New([]byte(rules)); err == nil {
+			t.Errorf("New(%s) succeeded", rules)
+		}
+	}
+}
+
+func TestDefault(t *testing.T) {
+	engine, err := New(bundledRules)
+	if err != nil {
+		t.Fatalf("bundled rules: %v", err)
+	}
+	if skipped := engine.Skipped(); len(skipped) > 0 {
+		t.Errorf("bundled rules skip patterns: %q", skipped)
+	}
+	if len(engine.technologies) == 0 {
+		t.Fatal("bundled rules are empty")
+	}
+	for _, tech := range engine.technologies {
+		for _, implied := range tech.implies {
+			if engine.byName[implied] == nil {
+				t.Errorf("%s implies unknown technology %q", tech.name, implied)
+			}
+		}
+	}
+	got := Default().Analyze(Response{Headers: map[string]string{"Server": "nginx/1.25.3"}})
+	if len(got) == 0 || got[0].Name != "Nginx" || got[0].Version != "1.25.3" {
+		t.Errorf("bundled rules on an nginx Server header = %+v", got)
+	}
+}
@@ -0,0 +1,85 @@
+package tlsprobe
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"net"
+	"time"
+)
+
+type CertificateInfo struct {
+	Subject      string    `json:"subject"`            //Distinguished name of the subject
+	SANs         []string  `json:"sans,omitempty"`     //DNS names, IP addresses and emails of the certificate
+	Issuer       string    `json:"issuer"`             //Distinguished name of the issuer
+	SerialNumber string    `json:"serial_number"`      //Serial number in hex
+	NotBefore    time.Time `json:"not_before"`         //Start of validity
+	NotAfter     time.Time `json:"not_after"`          //End of validity
+	Fingerprint  string    `json:"sha256_fingerprint"` //SHA-256 of the DER encoded certificate
+}
+
+type TLSInfo struct {
+	Version      string            `json:"version"`        //Negotiated protocol version
+	CipherSuite  string            `json:"cipher_suite"`   //Negotiated cipher suite
+	ALPN         string            `json:"alpn,omitempty"` //Negotiated application protocol
+	SNI          string            `json:"sni,omitempty"`  //Server name sent in the handshake
+	Certificates []CertificateInfo `json:"certificates"`   //Peer certificate chain, leaf first
+}
+
+// Probe opens a new connection to target and attempts a TLS handshake.
+// Certificates are not verified since the goal is to record them. When sni is
+// empty no server name is sent.
+func Probe(target string, sni string, timeout time.Duration) (*TLSInfo, error) {
+	conn, err := net.DialTimeout("tcp", target, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	config := &tls.Config{
+		ServerName:         sni,
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS10,
+		NextProtos:         []string{"h2", "http/1.1"},
+	}
+	tls_conn := tls.Client(conn, config)
+	tls_conn.SetDeadline(time.Now().Add(timeout))
+	if err := tls_conn.Handshake(); err != nil {
+		return nil, err
+	}
+	state := tls_conn.ConnectionState()
+
+	info := &TLSInfo{
+		Version:     tls.VersionName(state.Version),
+		CipherSuite: tls.CipherSuiteName(state.CipherSuite),
+		ALPN:        state.NegotiatedProtocol,
+		SNI:         sni,
+	}
+	for _, cert := range state.PeerCertificates {
+		info.Certificates = append(info.Certificates, certificateInfo(cert))
+	}
+	return info, nil
+}
+
+func certificateInfo(cert *x509.Certificate) CertificateInfo {
+	var sans []string
+	sans = append(sans, cert.DNSNames...)
+	for _, ip := range cert.IPAddresses {
+		sans = append(sans, ip.String())
+	}
+	sans = append(sans, cert.EmailAddresses...)
+	for _, uri := range cert.URIs {
+		sans = append(sans, uri.String())
+	}
+	fingerprint := sha256.Sum256(cert.Raw)
+	return CertificateInfo{
+		Subject:      cert.Subject.String(),
+		SANs:         sans,
+		Issuer:       cert.Issuer.String(),
+		SerialNumber: cert.SerialNumber.Text(16),
+		NotBefore:    cert.NotBefore,
+		NotAfter:     cert.NotAfter,
+		Fingerprint:  hex.EncodeToString(fingerprint[:]),
+	}
+}
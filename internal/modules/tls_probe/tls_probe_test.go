@@ -0,0 +1,85 @@
+package tlsprobe
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/hex"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	server := httptest.NewUnstartedServer(http.NotFoundHandler())
+	seen := make(chan string, 2) //Server names of the handshakes
+	server.TLS = &tls.Config{
+		GetConfigForClient: func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			seen <- hello.ServerName
+			return nil, nil
+		},
+	}
+	server.StartTLS()
+	defer server.Close()
+	target := server.Listener.Addr().String()
+
+	info, err := Probe(target, "example.com", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sni := <-seen; sni != "example.com" || info.SNI != "example.com" {
+		t.Errorf("server saw SNI %q, info has %q, want example.com", sni, info.SNI)
+	}
+	if info.Version != "TLS 1.3" || info.CipherSuite == "" || info.ALPN != "http/1.1" {
+		t.Errorf("handshake = %s %s alpn %q", info.Version, info.CipherSuite, info.ALPN)
+	}
+	if len(info.Certificates) != 1 {
+		t.Fatalf("%d certificates, want the test server's", len(info.Certificates))
+	}
+	cert, leaf := info.Certificates[0], server.Certificate()
+	fingerprint := sha256.Sum256(leaf.Raw)
+	if cert.Fingerprint != hex.EncodeToString(fingerprint[:]) || cert.SerialNumber != leaf.SerialNumber.Text(16) {
+		t.Errorf("certificate = %+v, does not identify the server's", cert)
+	}
+	if !slices.Contains(cert.SANs, "example.com") || !slices.Contains(cert.SANs, "127.0.0.1") {
+		t.Errorf("SANs = %q, want the DNS names and addresses of the certificate", cert.SANs)
+	}
+	if cert.Subject != leaf.Subject.String() || cert.Issuer != leaf.Issuer.String() || !cert.NotAfter.Equal(leaf.NotAfter) {
+		t.Errorf("certificate = %+v", cert)
+	}
+
+	if _, err := Probe(target, "", time.Second); err != nil {
+		t.Errorf("Probe without SNI error: %v", err)
+	} else if sni := <-seen; sni != "" {
+		t.Errorf("Probe without SNI sent %q", sni)
+	}
+}
+
+func TestProbeErrors(t *testing.T) {
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+	if _, err := Probe(plain.Listener.Addr().String(), "", time.Second); err == nil {
+		t.Error("handshake with a plain HTTP server succeeded")
+	}
+
+	silent, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	start := time.Now()
+	if _, err := Probe(silent.Addr().String(), "", 100*time.Millisecond); err == nil {
+		t.Error("handshake with a silent server succeeded")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("handshake timed out after %v, want the timeout", elapsed)
+	}
+
+	closed := silent.Addr().String()
+	silent.Close()
+	if _, err := Probe(closed, "", time.Second); err == nil {
+		t.Error("Probe of a closed port succeeded")
+	}
+}
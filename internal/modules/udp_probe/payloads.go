@@ -0,0 +1,110 @@
+package udpprobe
+
+import (
+	"encoding/binary"
+	"strconv"
+)
+
+type Payload struct {
+	Name    string //Name of the payload
+	Service string //Service expected to answer
+	Data    []byte //Datagram sent to the port
+}
+
+var payloads = map[int][]Payload{
+	53:    {{Name: "dns-version-bind", Service: "domain", Data: dnsQuery("version.bind", 16, 3)}},
+	123:   {{Name: "ntp-client", Service: "ntp", Data: ntpClient()}},
+	137:   {{Name: "netbios-nbstat", Service: "netbios-ns", Data: netbiosStat()}},
+	161:   {{Name: "snmp-v1-public", Service: "snmp", Data: snmpGetSysDescr("public")}},
+	500:   {{Name: "ike-main-mode", Service: "isakmp", Data: ikeMainMode()}},
+	1900:  {{Name: "ssdp-msearch", Service: "ssdp", Data: []byte("M-SEARCH * HTTP/1.1\r\nHOST: 239.255.255.250:1900\r\nMAN: \"ssdp:discover\"\r\nMX: 1\r\nST: ssdp:all\r\n\r\n")}},
+	5353:  {{Name: "mdns-services", Service: "mdns", Data: dnsQuery("_services._dns-sd._udp.local", 12, 1)}},
+	11211: {{Name: "memcached-version", Service: "memcache", Data: append([]byte{0x00, 0x01, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00}, "version\r\n"...)}},
+}
+
+// PayloadsFor returns the payloads sent to port, an empty datagram when none
+// is known.
+func PayloadsFor(port string) []Payload {
+	number, _ := strconv.Atoi(port)
+	if known, ok := payloads[number]; ok {
+		return known
+	}
+	return []Payload{{Name: "empty", Data: []byte{}}}
+}
+
+// dnsQuery builds a recursive DNS query for name with the given type and
+// class.
+func dnsQuery(name string, qtype uint16, qclass uint16) []byte {
+	msg := []byte{0x13, 0x37, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}
+	start := 0
+	for i := 0; i <= len(name); i++ {
+		if i == len(name) || name[i] == '.' {
+			msg = append(msg, byte(i-start))
+			msg = append(msg, name[start:i]...)
+			start = i + 1
+		}
+	}
+	msg = append(msg, 0x00)
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	return binary.BigEndian.AppendUint16(msg, qclass)
+}
+
+// ntpClient builds an NTPv3 client request.
+func ntpClient() []byte {
+	msg := make([]byte, 48)
+	msg[0] = 0x1b //LI 0, version 3, mode client
+	return msg
+}
+
+// netbiosStat builds a NetBIOS node status request for the wildcard name.
+func netbiosStat() []byte {
+	msg := []byte{0x13, 0x37, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x20}
+	msg = append(msg, "CKAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA"...) //"*" padded with nulls, half-ASCII encoded
+	return append(msg, 0x00, 0x00, 0x21, 0x00, 0x01)
+}
+
+// snmpGetSysDescr builds an SNMPv1 GetRequest for sysDescr.0.
+func snmpGetSysDescr(community string) []byte {
+	tlv := func(tag byte, value ...[]byte) []byte {
+		var content []byte
+		for _, v := range value {
+			content = append(content, v...)
+		}
+		return append([]byte{tag, byte(len(content))}, content...)
+	}
+	oid := tlv(0x06, []byte{0x2b, 0x06, 0x01, 0x02, 0x01, 0x01, 0x01, 0x00})
+	varbinds := tlv(0x30, tlv(0x30, oid, []byte{0x05, 0x00}))
+	pdu := tlv(0xa0, tlv(0x02, []byte{0x13, 0x37, 0x13, 0x37}), tlv(0x02, []byte{0x00}), tlv(0x02, []byte{0x00}), varbinds)
+	return tlv(0x30, tlv(0x02, []byte{0x00}), tlv(0x04, []byte(community)), pdu)
+}
+
+// ikeMainMode builds an IKEv1 main mode proposal offering 3DES, SHA1, a
+// pre-shared key and DH group 2.
+func ikeMainMode() []byte {
+	attributes := []uint16{
+		0x8001, 5, //Encryption 3DES
+		0x8002, 2, //Hash SHA1
+		0x8003, 1, //Authentication pre-shared key
+		0x8004, 2, //DH group 2
+		0x800b, 1, //Life type seconds
+		0x800c, 28800, //Life duration
+	}
+	var transform []byte
+	transform = append(transform, 0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00)
+	for _, attribute := range attributes {
+		transform = binary.BigEndian.AppendUint16(transform, attribute)
+	}
+	binary.BigEndian.PutUint16(transform[2:], uint16(len(transform)))
+
+	proposal := append([]byte{0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x00, 0x01}, transform...)
+	binary.BigEndian.PutUint16(proposal[2:], uint16(len(proposal)))
+
+	sa := append([]byte{0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x01}, proposal...)
+	binary.BigEndian.PutUint16(sa[2:], uint16(len(sa)))
+
+	header := []byte{0x13, 0x37, 0x13, 0x37, 0x13, 0x37, 0x13, 0x37} //Initiator cookie
+	header = append(header, make([]byte, 8)...)                      //Responder cookie
+	header = append(header, 0x01, 0x10, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00)
+	header = binary.BigEndian.AppendUint32(header, uint32(28+len(sa)))
+	return append(header, sa...)
+}
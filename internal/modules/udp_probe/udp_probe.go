@@ -0,0 +1,72 @@
+package udpprobe
+
+import (
+	"context"
+	"errors"
+	"net"
+	"syscall"
+	"time"
+)
+
+const (
+	StateOpen         = "open"          //Service answered
+	StateClosed       = "closed"        //ICMP port unreachable received
+	StateOpenFiltered = "open|filtered" //No answer, open or dropped on the way
+)
+
+const maxResponse = 4096 //Bytes read from a response
+
+// Result is the outcome of probing a single UDP port.
+type Result struct {
+	State    string //One of the state constants
+	Probe    string //Name of the payload that got the response
+	Service  string //Service the payload targets
+	Response []byte //Raw response
+	Err      error  //Error explaining a closed port
+}
+
+// Probe sends the payloads known for the port of target, each up to retries+1
+// times, and classifies the port from the replies. Ports without a known
+// payload get an empty datagram.
+func Probe(ctx context.Context, target string, timeout time.Duration, retries int) Result {
+	_, port_str, _ := net.SplitHostPort(target)
+	payloads := PayloadsFor(port_str)
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "udp", target)
+	if err != nil {
+		if ctx.Err() != nil { //Nothing was sent, like a cancellation between attempts
+			return Result{State: StateOpenFiltered}
+		}
+		return Result{State: StateClosed, Err: err}
+	}
+	defer conn.Close()
+
+	buf := make([]byte, maxResponse)
+	for _, payload := range payloads {
+		for attempt := 0; attempt <= retries; attempt++ {
+			if ctx.Err() != nil {
+				return Result{State: StateOpenFiltered}
+			}
+			conn.SetDeadline(time.Now().Add(timeout))
+			if _, err := conn.Write(payload.Data); err != nil {
+				if isRefused(err) {
+					return Result{State: StateClosed, Err: err}
+				}
+				continue
+			}
+			n, err := conn.Read(buf)
+			if err == nil {
+				return Result{State: StateOpen, Probe: payload.Name, Service: payload.Service, Response: append([]byte(nil), buf[:n]...)}
+			}
+			if isRefused(err) { //Connected UDP sockets report ICMP port unreachable as refused
+				return Result{State: StateClosed, Err: err}
+			}
+		}
+	}
+	return Result{State: StateOpenFiltered}
+}
+
+func isRefused(err error) bool {
+	return errors.Is(err, syscall.ECONNREFUSED)
+}
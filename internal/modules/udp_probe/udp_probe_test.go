@@ -0,0 +1,132 @@
+package udpprobe
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// listenUDP starts a UDP server on loopback handing every datagram to
+// respond, which returns the reply or nil to stay silent.
+func listenUDP(t *testing.T, respond func([]byte) []byte) (string, <-chan []byte) {
+	t.Helper()
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	received := make(chan []byte, 16)
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, from, err := conn.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			datagram := append([]byte(nil), buf[:n]...)
+			received <- datagram
+			if reply := respond(datagram); reply != nil {
+				conn.WriteToUDP(reply, from)
+			}
+		}
+	}()
+	return conn.LocalAddr().String(), received
+}
+
+func TestProbeOpen(t *testing.T) {
+	target, received := listenUDP(t, func([]byte) []byte { return []byte("pong") })
+	result := Probe(context.Background(), target, time.Second, 0)
+	if result.State != StateOpen || string(result.Response) != "pong" || result.Probe != "empty" {
+		t.Errorf("Probe = %+v, want open with the reply", result)
+	}
+	if datagram := <-received; len(datagram) != 0 {
+		t.Errorf("sent % x to a port without payload, want an empty datagram", datagram)
+	}
+}
+
+func TestProbeClosed(t *testing.T) {
+	conn, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target := conn.LocalAddr().String()
+	conn.Close() //Loopback answers with ICMP port unreachable
+	result := Probe(context.Background(), target, time.Second, 1)
+	if result.State != StateClosed || result.Err == nil {
+		t.Errorf("Probe = %+v, want closed with the refusal", result)
+	}
+}
+
+func TestProbeOpenFiltered(t *testing.T) {
+	target, received := listenUDP(t, func([]byte) []byte { return nil })
+	start := time.Now()
+	result := Probe(context.Background(), target, 100*time.Millisecond, 2)
+	if result.State != StateOpenFiltered {
+		t.Errorf("Probe = %+v, want open|filtered", result)
+	}
+	if elapsed := time.Since(start); elapsed < 300*time.Millisecond {
+		t.Errorf("gave up after %v, want 3 attempts of 100ms", elapsed)
+	}
+	if attempts := len(received); attempts != 3 {
+		t.Errorf("sent %d datagrams, want 3", attempts)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if result := Probe(ctx, target, time.Second, 2); result.State != StateOpenFiltered {
+		t.Errorf("Probe with a cancelled context = %+v, want open|filtered", result)
+	}
+}
+
+func TestPayloadsFor(t *testing.T) {
+	tests := []struct {
+		port    int
+		name    string
+		service string
+		prefix  []byte
+	}{
+		{53, "dns-version-bind", "domain", []byte{0x13, 0x37, 0x01, 0x00, 0x00, 0x01}},
+		{123, "ntp-client", "ntp", []byte{0x1b}},
+		{137, "netbios-nbstat", "netbios-ns", []byte{0x13, 0x37, 0x00, 0x00, 0x00, 0x01}},
+		{161, "snmp-v1-public", "snmp", []byte{0x30}},
+		{500, "ike-main-mode", "isakmp", []byte{0x13, 0x37, 0x13, 0x37}},
+		{1900, "ssdp-msearch", "ssdp", []byte("M-SEARCH * HTTP/1.1\r\n")},
+		{5353, "mdns-services", "mdns", []byte{0x13, 0x37}},
+		{11211, "memcached-version", "memcache", []byte{0x00, 0x01}},
+		{9999, "empty", "", nil},
+	}
+	for _, test := range tests {
+		payloads := PayloadsFor(strconv.Itoa(test.port))
+		if len(payloads) == 0 {
+			t.Errorf("PayloadsFor(%d) is empty", test.port)
+			continue
+		}
+		payload := payloads[0]
+		if payload.Name != test.name || payload.Service != test.service || !bytes.HasPrefix(payload.Data, test.prefix) {
+			t.Errorf("PayloadsFor(%d) = %s/%s % x, want %s/%s starting % x", test.port, payload.Name, payload.Service, payload.Data, test.name, test.service, test.prefix)
+		}
+	}
+	if payloads := PayloadsFor("not a port"); len(payloads) != 1 || payloads[0].Name != "empty" {
+		t.Errorf("PayloadsFor(invalid) = %+v, want the empty datagram", payloads)
+	}
+}
+
+func TestPayloadEncoding(t *testing.T) {
+	query := dnsQuery("version.bind", 16, 3)
+	want := append([]byte{0x13, 0x37, 0x01, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}, "\x07version\x04bind\x00\x00\x10\x00\x03"...)
+	if !bytes.Equal(query, want) {
+		t.Errorf("dnsQuery = % x, want % x", query, want)
+	}
+	if snmp := snmpGetSysDescr("public"); int(snmp[1]) != len(snmp)-2 || !bytes.Contains(snmp, []byte("\x04\x06public")) {
+		t.Errorf("snmpGetSysDescr = % x, malformed", snmp)
+	}
+	if ike := ikeMainMode(); int(ike[24])<<24|int(ike[25])<<16|int(ike[26])<<8|int(ike[27]) != len(ike) {
+		t.Errorf("ikeMainMode length field does not match % x", ike)
+	}
+	if ntp := ntpClient(); len(ntp) != 48 {
+		t.Errorf("ntpClient is %d bytes, want 48", len(ntp))
+	}
+}
@@ -0,0 +1,32 @@
+package output
+
+import (
+	"io"
+
+	"github.com/efecankaya/go-port-scanner/internal/report"
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+// htmlWriter buffers every result and writes a self-contained HTML report on
+// Close.
+type htmlWriter struct {
+	w       io.Writer
+	results []scanner.TargetResult
+}
+
+func (h *htmlWriter) WriteResult(result scanner.TargetResult) error {
+	h.results = append(h.results, result)
+	return nil
+}
+
+func (h *htmlWriter) Close(summary Summary) error {
+	overview := report.Overview{
+		StartTime:   summary.StartTime,
+		EndTime:     summary.EndTime,
+		Targets:     summary.Targets,
+		Interrupted: summary.Interrupted,
+		HostsUp:     summary.HostsUp,
+		HostsDown:   summary.HostsDown,
+	}
+	return report.WriteHTML(h.w, report.Build(h.results, summary.OperatingSystems), overview)
+}
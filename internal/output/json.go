@@ -0,0 +1,91 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+// jsonWriter writes a single JSON document, streaming the elements of its
+// results array as they arrive. The summary is only known once the scan is
+// over so it follows the results.
+type jsonWriter struct {
+	w       io.Writer
+	started bool //Results array opened
+	buf     bytes.Buffer
+	enc     *json.Encoder
+}
+
+// encode indents value for a place in the document prefix deep, without the
+// trailing newline of the encoder.
+func (j *jsonWriter) encode(value any, prefix string) ([]byte, error) {
+	if j.enc == nil {
+		j.enc = json.NewEncoder(&j.buf)
+		j.enc.SetEscapeHTML(false)
+	}
+	j.enc.SetIndent(prefix, "  ")
+	j.buf.Reset()
+	if err := j.enc.Encode(value); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(j.buf.Bytes(), []byte("\n")), nil
+}
+
+func (j *jsonWriter) WriteResult(result scanner.TargetResult) error {
+	element, err := j.encode(result, "    ")
+	if err != nil {
+		return err
+	}
+	separator := ",\n    "
+	if !j.started {
+		separator = "{\n  \"results\": [\n    "
+		j.started = true
+	}
+	_, err = j.w.Write(append([]byte(separator), element...))
+	return err
+}
+
+func (j *jsonWriter) Close(summary Summary) error {
+	head := "\n  ],\n"
+	if !j.started {
+		head = "{\n  \"results\": [],\n"
+	}
+	doc, err := j.encode(summary, "  ")
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(j.w, head+"  \"summary\": "+string(doc)+"\n}\n")
+	return err
+}
+
+// jsonlWriter writes one JSON object per line as soon as a result arrives.
+// Every line carries a "type" field so results and the trailing summary can
+// be told apart.
+type jsonlWriter struct {
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func (j *jsonlWriter) encoder() *json.Encoder {
+	if j.enc == nil {
+		j.enc = json.NewEncoder(j.w)
+		j.enc.SetEscapeHTML(false)
+	}
+	return j.enc
+}
+
+func (j *jsonlWriter) WriteResult(result scanner.TargetResult) error {
+	return j.encoder().Encode(struct {
+		Type string `json:"type"`
+		scanner.TargetResult
+	}{"result", result})
+}
+
+func (j *jsonlWriter) Close(summary Summary) error {
+	return j.encoder().Encode(struct {
+		Type string `json:"type"`
+		Summary
+	}{"summary", summary})
+}
@@ -0,0 +1,69 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+func TestJSONStreams(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := New("json", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := fixtureResults()
+	for i, result := range results {
+		if err := w.WriteResult(result); err != nil {
+			t.Fatal(err)
+		}
+		if written := bytes.Count(buf.Bytes(), []byte(`"port": `)); written != i+1 {
+			t.Fatalf("result %d not written before Close:\n%s", i, buf.String())
+		}
+	}
+	if err := w.Close(fixtureSummary()); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc struct {
+		Results []scanner.TargetResult `json:"results"`
+		Summary Summary                `json:"summary"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output does not decode: %v\n%s", err, buf.String())
+	}
+	if len(doc.Results) != len(results) || doc.Results[1].Hostname != "admin.example.com" {
+		t.Errorf("decoded %d results, want %d in order", len(doc.Results), len(results))
+	}
+	if doc.Summary.Flags["p"] != "22,80,443" {
+		t.Errorf("summary = %+v", doc.Summary)
+	}
+
+	//Streamed pieces line up with a document indented in one go
+	var compact, indented bytes.Buffer
+	if err := json.Compact(&compact, buf.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	json.Indent(&indented, compact.Bytes(), "", "  ")
+	indented.WriteByte('\n')
+	if indented.String() != buf.String() {
+		t.Errorf("output is not indented consistently:\n%s", buf.String())
+	}
+}
+
+func TestJSONEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := New("json", &buf)
+	if err := w.Close(Summary{}); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("output does not decode: %v\n%s", err, buf.String())
+	}
+	if string(doc["results"]) != "[]" {
+		t.Errorf("results = %s, want an empty array", doc["results"])
+	}
+}
@@ -0,0 +1,33 @@
+package output
+
+import (
+	"errors"
+
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+type multiWriter []Writer
+
+// Multi returns a Writer that duplicates results to every given writer.
+func Multi(writers ...Writer) Writer {
+	if len(writers) == 1 {
+		return writers[0]
+	}
+	return multiWriter(writers)
+}
+
+func (m multiWriter) WriteResult(result scanner.TargetResult) error {
+	var errs []error
+	for _, w := range m {
+		errs = append(errs, w.WriteResult(result))
+	}
+	return errors.Join(errs...)
+}
+
+func (m multiWriter) Close(summary Summary) error {
+	var errs []error
+	for _, w := range m {
+		errs = append(errs, w.Close(summary))
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+// Summary describes a single scan run.
+type Summary struct {
+	StartTime time.Time         `json:"start_time"` //Time the scan was started
+	EndTime   time.Time         `json:"end_time"`   //Time the scan was finished
+	Targets   []string          `json:"targets"`    //Target specifications as given: addresses, ranges and domain names
+	Ports     []int             `json:"ports"`      //Scanned ports
+	Flags     map[string]string `json:"flags"`      //Flags set by the user
+
+	Resolved map[string][]string `json:"resolved,omitempty"` //Addresses of the scanned domain names
+
+	Interrupted bool `json:"interrupted,omitempty"` //Scan was stopped before finishing
+	HostsUp     int  `json:"hosts_up,omitempty"`    //Hosts found alive by discovery
+	HostsDown   int  `json:"hosts_down,omitempty"`  //Hosts skipped by discovery
+
+	OperatingSystems map[string]scanner.OSGuess `json:"operating_systems,omitempty"` //Operating system guessed per host
+}
+
+// DualStack returns the domain names that resolved to both IPv4 and IPv6
+// addresses, sorted.
+func (s Summary) DualStack() []string {
+	var names []string
+	for name, addresses := range s.Resolved {
+		v4, v6 := false, false
+		for _, address := range addresses {
+			if ip := net.ParseIP(address); ip != nil && ip.To4() != nil {
+				v4 = true
+			} else if ip != nil {
+				v6 = true
+			}
+		}
+		if v4 && v6 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Writer receives scan results as they are produced and finalizes the
+// output once the scan is over.
+type Writer interface {
+	WriteResult(result scanner.TargetResult) error
+	Close(summary Summary) error
+}
+
+// Formats lists the output formats accepted by New.
+var Formats = []string{"text", "table", "json", "jsonl", "html", "xml"}
+
+// New returns a Writer for the given format writing to w.
+func New(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case "text":
+		return &textWriter{w: w}, nil
+	case "table":
+		return &tableWriter{w: w}, nil
+	case "json":
+		return &jsonWriter{w: w}, nil
+	case "jsonl":
+		return &jsonlWriter{w: w}, nil
+	case "html":
+		return &htmlWriter{w: w}, nil
+	case "xml":
+		return &xmlWriter{w: w}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
@@ -0,0 +1,43 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/efecankaya/go-port-scanner/internal/report"
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+// tableWriter buffers every result and writes a table per host on Close.
+type tableWriter struct {
+	w       io.Writer
+	results []scanner.TargetResult
+}
+
+func (t *tableWriter) WriteResult(result scanner.TargetResult) error {
+	t.results = append(t.results, result)
+	return nil
+}
+
+func (t *tableWriter) Close(summary Summary) error {
+	hosts := report.Build(t.results, summary.OperatingSystems)
+	if err := report.WriteTable(t.w, hosts); err != nil {
+		return err
+	}
+	open := 0
+	for _, host := range hosts {
+		open += host.OpenPorts()
+	}
+	status := "finished"
+	if summary.Interrupted {
+		status = "interrupted"
+	}
+	if len(hosts) > 0 {
+		if _, err := fmt.Fprintln(t.w); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(t.w, "Scan %s after %s, %d open ports on %d hosts\n", status, summary.EndTime.Sub(summary.StartTime).Round(time.Millisecond), open, len(hosts))
+	return err
+}
@@ -0,0 +1,66 @@
+package output
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestTableGolden(t *testing.T) {
+	got := writeAll(t, "table", fixtureResults(), fixtureSummary())
+	golden := "testdata/scan.table"
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("table output differs from %s, rerun with -update after checking it:\n%s", golden, got)
+	}
+}
+
+func TestTableSummaryLine(t *testing.T) {
+	summary := fixtureSummary()
+	summary.Interrupted = true
+	lines := strings.Split(strings.TrimSpace(writeAll(t, "table", fixtureResults(), summary)), "\n")
+	if last := lines[len(lines)-1]; last != "Scan interrupted after 12.5s, 2 open ports on 2 hosts" {
+		t.Errorf("last line = %q", last)
+	}
+	if got := writeAll(t, "table", nil, Summary{}); got != "Scan finished after 0s, 0 open ports on 0 hosts\n" {
+		t.Errorf("empty scan = %q", got)
+	}
+}
+
+func TestHTMLWriter(t *testing.T) {
+	summary := fixtureSummary()
+	summary.Interrupted = true
+	summary.HostsUp, summary.HostsDown = 2, 5
+	page := writeAll(t, "html", fixtureResults(), summary)
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		"Targets: 192.0.2.10, 2001:db8::1",
+		", interrupted",
+		"Hosts up, 5 down",
+		"www.example.com", "admin.example.com", "2001:db8::1",
+		"OpenSSH 9.6", "Welcome &amp; &lt;hello&gt;",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(page, "<hello>") {
+		t.Error("page title was not escaped")
+	}
+	if strings.Contains(page, "src=\"http") || strings.Contains(page, "href=\"http") {
+		t.Error("report loads external resources")
+	}
+
+	empty := writeAll(t, "html", nil, Summary{})
+	if !strings.Contains(empty, "</html>") {
+		t.Error("report of an empty scan is incomplete")
+	}
+}
@@ -0,0 +1,109 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/efecankaya/go-port-scanner/internal/report"
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+type textWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (t *textWriter) WriteResult(result scanner.TargetResult) error {
+	t.count++
+	_, err := io.WriteString(t.w, formatText(result))
+	return err
+}
+
+// formatText renders a result as a heading line with the port, state and
+// service followed by an indented line per finding.
+func formatText(result scanner.TargetResult) string {
+	var b strings.Builder
+	target := net.JoinHostPort(result.HostIP, strconv.Itoa(result.Port)) + "/" + result.Protocol
+	if result.Hostname != "" {
+		target += " (" + result.Hostname + ")"
+	}
+	fmt.Fprintf(&b, "%s %s", target, result.State)
+	if result.Service != nil {
+		port := report.Port{TargetResult: result}
+		fmt.Fprintf(&b, " %s", port.ServiceName())
+		if version := port.Version(); version != "" {
+			fmt.Fprintf(&b, " %s", version)
+		}
+	}
+	b.WriteByte('\n')
+
+	if result.Banner != "" {
+		banner := strings.ReplaceAll(strings.TrimRight(result.Banner, "\r\n"), "\r\n", "\n")
+		fmt.Fprintf(&b, "  banner: %s\n", strings.ReplaceAll(banner, "\n", "\n          "))
+	}
+	if result.TLS != nil {
+		fmt.Fprintf(&b, "  tls: %s %s\n", result.TLS.Version, result.TLS.CipherSuite)
+		if len(result.TLS.Certificates) > 0 {
+			cert := result.TLS.Certificates[0]
+			fmt.Fprintf(&b, "  certificate: subject %s, issuer %s, expires %s\n", cert.Subject, cert.Issuer, cert.NotAfter.Format("2006-01-02"))
+			if len(cert.SANs) > 0 {
+				fmt.Fprintf(&b, "  sans: %s\n", strings.Join(cert.SANs, ", "))
+			}
+		}
+	}
+	if result.HttpValid {
+		fmt.Fprintf(&b, "  http: %d", result.HttpStatusCode)
+		if result.HttpTitle != "" {
+			fmt.Fprintf(&b, " %q", result.HttpTitle)
+		}
+		b.WriteByte('\n')
+	}
+	if result.Favicon != nil {
+		fmt.Fprintf(&b, "  favicon: mmh3 %d, %s\n", result.Favicon.MMH3, result.Favicon.URL)
+	}
+	if len(result.Technologies) > 0 {
+		fmt.Fprintf(&b, "  technologies: %s\n", report.FormatTechnologies(result.Technologies))
+	}
+	if result.OperatingSystem != "" {
+		fmt.Fprintf(&b, "  os: %s\n", result.OperatingSystem)
+	}
+	if result.Error != "" {
+		fmt.Fprintf(&b, "  error: %s\n", result.Error)
+	}
+	return b.String()
+}
+
+func (t *textWriter) Close(summary Summary) error {
+	status := "finished"
+	if summary.Interrupted {
+		status = "interrupted"
+	}
+	hosts := ""
+	if summary.HostsUp > 0 || summary.HostsDown > 0 {
+		hosts = fmt.Sprintf(", %d of %d hosts up", summary.HostsUp, summary.HostsUp+summary.HostsDown)
+	}
+	if _, err := fmt.Fprintf(t.w, "Scan %s after %s, %d results%s\n", status, summary.EndTime.Sub(summary.StartTime).Round(time.Millisecond), t.count, hosts); err != nil {
+		return err
+	}
+	for _, name := range summary.DualStack() {
+		if _, err := fmt.Fprintf(t.w, "%s is dual-stack: %s\n", name, strings.Join(summary.Resolved[name], ", ")); err != nil {
+			return err
+		}
+	}
+	hosts_os := make([]string, 0, len(summary.OperatingSystems))
+	for host := range summary.OperatingSystems {
+		hosts_os = append(hosts_os, host)
+	}
+	sort.Strings(hosts_os)
+	for _, host := range hosts_os {
+		if _, err := fmt.Fprintf(t.w, "%s runs %s\n", host, summary.OperatingSystems[host]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
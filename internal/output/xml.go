@@ -0,0 +1,359 @@
+package output
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/efecankaya/go-port-scanner/data"
+	"github.com/efecankaya/go-port-scanner/internal/report"
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+// xmlWriter buffers every result and writes a document in the layout of nmap
+// XML output on Close, for tools that import nmap scans.
+type xmlWriter struct {
+	w       io.Writer
+	results []scanner.TargetResult
+}
+
+// The types below follow the nmap DTD, only the elements we have data for
+// are written.
+type (
+	nmapRun struct {
+		XMLName          xml.Name     `xml:"nmaprun"`
+		Scanner          string       `xml:"scanner,attr"`
+		Args             string       `xml:"args,attr"`
+		Start            int64        `xml:"start,attr"`
+		StartStr         string       `xml:"startstr,attr"`
+		Version          string       `xml:"version,attr"`
+		XMLOutputVersion string       `xml:"xmloutputversion,attr"`
+		ScanInfo         []nmapInfo   `xml:"scaninfo"`
+		Verbose          nmapLevel    `xml:"verbose"`
+		Debugging        nmapLevel    `xml:"debugging"`
+		Hosts            []nmapHost   `xml:"host"`
+		RunStats         nmapRunStats `xml:"runstats"`
+	}
+	nmapInfo struct {
+		Type        string `xml:"type,attr"`
+		Protocol    string `xml:"protocol,attr"`
+		NumServices int    `xml:"numservices,attr"`
+		Services    string `xml:"services,attr"`
+	}
+	nmapLevel struct {
+		Level int `xml:"level,attr"`
+	}
+	nmapHost struct {
+		StartTime int64          `xml:"starttime,attr"`
+		EndTime   int64          `xml:"endtime,attr"`
+		Status    nmapStatus     `xml:"status"`
+		Address   nmapAddress    `xml:"address"`
+		Hostnames []nmapHostname `xml:"hostnames>hostname"`
+		Ports     []nmapPort     `xml:"ports>port"`
+		OS        *nmapOS        `xml:"os,omitempty"`
+	}
+	nmapStatus struct {
+		State     string `xml:"state,attr"`
+		Reason    string `xml:"reason,attr"`
+		ReasonTTL int    `xml:"reason_ttl,attr"`
+	}
+	nmapAddress struct {
+		Addr     string `xml:"addr,attr"`
+		AddrType string `xml:"addrtype,attr"`
+	}
+	nmapHostname struct {
+		Name string `xml:"name,attr"`
+		Type string `xml:"type,attr"`
+	}
+	nmapPort struct {
+		Protocol string       `xml:"protocol,attr"`
+		PortID   int          `xml:"portid,attr"`
+		State    nmapStatus   `xml:"state"`
+		Service  *nmapService `xml:"service,omitempty"`
+		Scripts  []nmapScript `xml:"script"`
+	}
+	nmapService struct {
+		Name    string `xml:"name,attr"`
+		Product string `xml:"product,attr,omitempty"`
+		Version string `xml:"version,attr,omitempty"`
+		Tunnel  string `xml:"tunnel,attr,omitempty"`
+		Method  string `xml:"method,attr"`
+		Conf    int    `xml:"conf,attr"`
+	}
+	nmapScript struct {
+		ID     string `xml:"id,attr"`
+		Output string `xml:"output,attr"`
+	}
+	nmapOS struct {
+		Matches []nmapOSMatch `xml:"osmatch"`
+	}
+	nmapOSMatch struct {
+		Name     string      `xml:"name,attr"`
+		Accuracy int         `xml:"accuracy,attr"`
+		Line     int         `xml:"line,attr"`
+		Class    nmapOSClass `xml:"osclass"`
+	}
+	nmapOSClass struct {
+		Type     string `xml:"type,attr"`
+		Vendor   string `xml:"vendor,attr"`
+		OSFamily string `xml:"osfamily,attr"`
+		Accuracy int    `xml:"accuracy,attr"`
+	}
+	nmapRunStats struct {
+		Finished nmapFinished `xml:"finished"`
+		Hosts    nmapHosts    `xml:"hosts"`
+	}
+	nmapFinished struct {
+		Time    int64  `xml:"time,attr"`
+		TimeStr string `xml:"timestr,attr"`
+		Elapsed string `xml:"elapsed,attr"`
+		Summary string `xml:"summary,attr"`
+		Exit    string `xml:"exit,attr"`
+	}
+	nmapHosts struct {
+		Up    int `xml:"up,attr"`
+		Down  int `xml:"down,attr"`
+		Total int `xml:"total,attr"`
+	}
+)
+
+// scannerName identifies this tool in the scanner attribute. Only the layout
+// of the document follows nmap.
+const scannerName = "go-port-scanner"
+
+// scannerVersion is the module version of the binary, "(devel)" for local
+// builds.
+func scannerVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+		return info.Main.Version
+	}
+	return "(devel)"
+}
+
+// tlsServices maps the names of services wrapped in TLS to the service nmap
+// reports along with tunnel="ssl".
+var tlsServices = map[string]string{
+	"https":       "http",
+	"imaps":       "imap",
+	"pop3s":       "pop3",
+	"ldaps":       "ldap",
+	"ftps":        "ftp",
+	"smtps":       "smtp",
+	"submissions": "smtp",
+	"telnets":     "telnet",
+	"ircs":        "irc",
+	"nntps":       "nntp",
+}
+
+// osVendors maps operating system families to the vendor nmap reports.
+var osVendors = map[string]string{
+	"Linux":   "Linux",
+	"Windows": "Microsoft",
+	"macOS":   "Apple",
+}
+
+func (x *xmlWriter) WriteResult(result scanner.TargetResult) error {
+	x.results = append(x.results, result)
+	return nil
+}
+
+func (x *xmlWriter) Close(summary Summary) error {
+	hosts := report.Build(x.results, summary.OperatingSystems)
+	elapsed := summary.EndTime.Sub(summary.StartTime)
+	run := nmapRun{
+		Scanner:          scannerName,
+		Args:             commandLine(summary.Flags),
+		Start:            summary.StartTime.Unix(),
+		StartStr:         summary.StartTime.Format(time.ANSIC),
+		Version:          scannerVersion(),
+		XMLOutputVersion: "1.05",
+		ScanInfo:         []nmapInfo{scanInfo(summary)},
+	}
+	for _, host := range hosts {
+		run.Hosts = append(run.Hosts, nmapHostOf(host, summary))
+	}
+
+	up, down := len(hosts), 0
+	if summary.HostsUp > 0 || summary.HostsDown > 0 {
+		up, down = summary.HostsUp, summary.HostsDown
+	}
+	exit := "success"
+	if summary.Interrupted {
+		exit = "error"
+	}
+	run.RunStats = nmapRunStats{
+		Finished: nmapFinished{
+			Time:    summary.EndTime.Unix(),
+			TimeStr: summary.EndTime.Format(time.ANSIC),
+			Elapsed: strconv.FormatFloat(elapsed.Seconds(), 'f', 2, 64),
+			Summary: fmt.Sprintf("Scan done at %s; %d IP addresses (%d hosts up) scanned in %.2f seconds", summary.EndTime.Format(time.ANSIC), up+down, up, elapsed.Seconds()),
+			Exit:    exit,
+		},
+		Hosts: nmapHosts{Up: up, Down: down, Total: up + down},
+	}
+
+	if _, err := io.WriteString(x.w, xml.Header+"<!DOCTYPE nmaprun>\n"); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(x.w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		return err
+	}
+	_, err := io.WriteString(x.w, "\n")
+	return err
+}
+
+func nmapHostOf(host report.Host, summary Summary) nmapHost {
+	entry := nmapHost{
+		StartTime: summary.StartTime.Unix(),
+		EndTime:   summary.EndTime.Unix(),
+		Status:    nmapStatus{State: "up", Reason: "user-set"},
+		Address:   nmapAddress{Addr: host.IP, AddrType: "ipv4"},
+	}
+	if ip := net.ParseIP(host.IP); ip != nil && ip.To4() == nil {
+		entry.Address.AddrType = "ipv6"
+	}
+	names := append([]string(nil), host.Hostnames...)
+	for _, port := range host.Ports { //Virtual hosts requested on the web ports
+		for _, result := range append([]scanner.TargetResult{port.TargetResult}, port.VHosts...) {
+			if result.Hostname != "" && !slices.Contains(names, result.Hostname) {
+				names = append(names, result.Hostname)
+			}
+		}
+	}
+	for _, name := range names {
+		entry.Hostnames = append(entry.Hostnames, nmapHostname{Name: name, Type: "user"})
+	}
+	for _, port := range host.Ports {
+		entry.Ports = append(entry.Ports, nmapPortOf(port))
+	}
+	if host.OS != nil {
+		name := strings.TrimSpace(host.OS.Family + " " + host.OS.Version)
+		entry.OS = &nmapOS{Matches: []nmapOSMatch{{
+			Name:     name,
+			Accuracy: host.OS.Confidence,
+			Class:    nmapOSClass{Type: "general purpose", Vendor: osVendors[host.OS.Family], OSFamily: host.OS.Family, Accuracy: host.OS.Confidence},
+		}}}
+	}
+	return entry
+}
+
+func nmapPortOf(port report.Port) nmapPort {
+	entry := nmapPort{
+		Protocol: port.Protocol,
+		PortID:   port.Port,
+		State:    nmapStatus{State: port.State, Reason: stateReason(port.TargetResult), ReasonTTL: port.TTL},
+	}
+	if port.Service != nil {
+		entry.Service = &nmapService{
+			Name:    port.Service.Name,
+			Product: port.Service.Product,
+			Version: port.Service.Version,
+			Method:  "table",
+			Conf:    port.Service.Confidence,
+		}
+		if port.Service.Method == "probe" {
+			entry.Service.Method = "probed"
+		}
+	} else if name, ok := data.PortToService[port.Port]; ok {
+		entry.Service = &nmapService{Name: name, Method: "table", Conf: 3}
+	}
+	if entry.Service != nil && port.TLS != nil {
+		entry.Service.Tunnel = "ssl"
+		if base, ok := tlsServices[entry.Service.Name]; ok {
+			entry.Service.Name = base
+		}
+	}
+	if port.Banner != "" {
+		entry.Scripts = append(entry.Scripts, nmapScript{ID: "banner", Output: port.Banner})
+	}
+	if port.HttpTitle != "" {
+		entry.Scripts = append(entry.Scripts, nmapScript{ID: "http-title", Output: port.HttpTitle})
+	}
+	if port.TLS != nil && len(port.TLS.Certificates) > 0 {
+		cert := port.TLS.Certificates[0]
+		output := fmt.Sprintf("Subject: %s\nIssuer: %s\nNot valid before: %s\nNot valid after:  %s",
+			cert.Subject, cert.Issuer, cert.NotBefore.UTC().Format("2006-01-02T15:04:05"), cert.NotAfter.UTC().Format("2006-01-02T15:04:05"))
+		if len(cert.SANs) > 0 {
+			output += "\nSubject Alternative Name: " + strings.Join(cert.SANs, ", ")
+		}
+		entry.Scripts = append(entry.Scripts, nmapScript{ID: "ssl-cert", Output: output})
+	}
+	if len(port.Technologies) > 0 {
+		entry.Scripts = append(entry.Scripts, nmapScript{ID: "http-technologies", Output: report.FormatTechnologies(port.Technologies)})
+	}
+	return entry
+}
+
+// stateReason gives the nmap reason of a port state.
+func stateReason(result scanner.TargetResult) string {
+	switch {
+	case result.Protocol == "udp" && result.State == scanner.StateOpen:
+		return "udp-response"
+	case result.Protocol == "udp":
+		return "no-response"
+	case result.State == scanner.StateOpen:
+		return "syn-ack"
+	case result.State == scanner.StateClosed:
+		return "conn-refused"
+	}
+	return "no-response"
+}
+
+// scanInfo describes the scan type and its ports the way nmap does.
+func scanInfo(summary Summary) nmapInfo {
+	info := nmapInfo{Type: "connect", Protocol: "tcp", NumServices: len(summary.Ports), Services: portRanges(summary.Ports)}
+	switch {
+	case summary.Flags["sU"] == "true":
+		info.Type, info.Protocol = "udp", "udp"
+	case summary.Flags["sS"] == "true":
+		info.Type = "syn"
+	}
+	return info
+}
+
+// portRanges compresses ports into nmap's services list, such as 1-1024,8080.
+func portRanges(ports []int) string {
+	sorted := append([]int(nil), ports...)
+	sort.Ints(sorted)
+	var ranges []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] <= sorted[j]+1 {
+			j++
+		}
+		if sorted[i] == sorted[j] {
+			ranges = append(ranges, strconv.Itoa(sorted[i]))
+		} else {
+			ranges = append(ranges, strconv.Itoa(sorted[i])+"-"+strconv.Itoa(sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+// commandLine rebuilds the arguments of the scan from the flags set.
+func commandLine(flags map[string]string) string {
+	names := make([]string, 0, len(flags))
+	for name := range flags {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	args := []string{"port-scanner"}
+	for _, name := range names {
+		if flags[name] == "true" {
+			args = append(args, "-"+name)
+		} else {
+			args = append(args, "-"+name+" "+flags[name])
+		}
+	}
+	return strings.Join(args, " ")
+}
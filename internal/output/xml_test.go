@@ -0,0 +1,187 @@
+package output
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+// fixtureResults is a scan of two hosts, one with a web server answering for
+// a second virtual host.
+func fixtureResults() []scanner.TargetResult {
+	return []scanner.TargetResult{
+		{
+			HostIP:    "192.0.2.10",
+			Hostnames: []string{"www.example.com"},
+			Hostname:  "www.example.com",
+			Port:      443,
+			Protocol:  "tcp",
+			State:     scanner.StateOpen,
+			Service:   &scanner.ServiceInfo{Name: "https", Product: "nginx", Version: "1.25.3", Confidence: 90, Method: "probe"},
+			TLS: &scanner.TLSInfo{Version: "TLS 1.3", Certificates: []scanner.CertificateInfo{{
+				Subject:   "CN=www.example.com",
+				Issuer:    "CN=Example CA",
+				NotBefore: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+				NotAfter:  time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC),
+			}}},
+			HttpValid:      true,
+			HttpStatusCode: 200,
+			HttpTitle:      "Welcome & <hello>",
+		},
+		{
+			HostIP:         "192.0.2.10",
+			Hostnames:      []string{"www.example.com"},
+			Hostname:       "admin.example.com",
+			Port:           443,
+			Protocol:       "tcp",
+			State:          scanner.StateOpen,
+			HttpValid:      true,
+			HttpStatusCode: 302,
+		},
+		{
+			HostIP:    "192.0.2.10",
+			Hostnames: []string{"www.example.com"},
+			Port:      22,
+			Protocol:  "tcp",
+			State:     scanner.StateOpen,
+			Banner:    "SSH-2.0-OpenSSH_9.6",
+			Service:   &scanner.ServiceInfo{Name: "ssh", Product: "OpenSSH", Version: "9.6", Confidence: 95},
+		},
+		{
+			HostIP:   "2001:db8::1",
+			Port:     80,
+			Protocol: "tcp",
+			State:    scanner.StateClosed,
+		},
+	}
+}
+
+func fixtureSummary() Summary {
+	start := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	return Summary{
+		StartTime: start,
+		EndTime:   start.Add(12500 * time.Millisecond),
+		Targets:   []string{"192.0.2.10", "2001:db8::1"},
+		Ports:     []int{22, 80, 443},
+		Flags:     map[string]string{"ip": "192.0.2.10,2001:db8::1", "p": "22,80,443"},
+		OperatingSystems: map[string]scanner.OSGuess{
+			"192.0.2.10": {Family: "Linux", Confidence: 80},
+		},
+	}
+}
+
+// writeAll feeds results to the writer of format and returns the output.
+func writeAll(t *testing.T, format string, results []scanner.TargetResult, summary Summary) string {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := New(format, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, result := range results {
+		if err := w.WriteResult(result); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(summary); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestXMLGolden(t *testing.T) {
+	got := writeAll(t, "xml", fixtureResults(), fixtureSummary())
+	golden := "testdata/scan.xml"
+	if *update {
+		if err := os.WriteFile(golden, []byte(got), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != string(want) {
+		t.Errorf("XML output differs from %s, rerun with -update after checking it:\n%s", golden, got)
+	}
+}
+
+// TestXMLStructure decodes the output again and checks it has the element
+// order and attributes tools importing nmap scans rely on.
+func TestXMLStructure(t *testing.T) {
+	output := writeAll(t, "xml", fixtureResults(), fixtureSummary())
+
+	var run nmapRun
+	if err := xml.Unmarshal([]byte(output), &run); err != nil {
+		t.Fatalf("output does not decode: %v", err)
+	}
+	if len(run.Hosts) != 2 {
+		t.Fatalf("%d hosts, want 2", len(run.Hosts))
+	}
+	host := run.Hosts[0]
+	if host.Address != (nmapAddress{Addr: "192.0.2.10", AddrType: "ipv4"}) {
+		t.Errorf("address = %+v", host.Address)
+	}
+	if run.Hosts[1].Address.AddrType != "ipv6" {
+		t.Errorf("address type of %s = %q, want ipv6", run.Hosts[1].Address.Addr, run.Hosts[1].Address.AddrType)
+	}
+	var names []string
+	for _, name := range host.Hostnames {
+		names = append(names, name.Name)
+	}
+	if want := []string{"www.example.com", "admin.example.com"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("hostnames = %q, want %q", names, want)
+	}
+	if len(host.Ports) != 2 || host.Ports[0].PortID != 22 || host.Ports[1].PortID != 443 {
+		t.Fatalf("ports = %+v, want 22 and 443 once each", host.Ports)
+	}
+	https := host.Ports[1].Service
+	if https == nil || https.Name != "http" || https.Tunnel != "ssl" || https.Method != "probed" {
+		t.Errorf("service of 443 = %+v, want http over ssl, probed", https)
+	}
+	if ssh := host.Ports[0].Service; ssh == nil || ssh.Tunnel != "" {
+		t.Errorf("service of 22 = %+v, want no tunnel", ssh)
+	}
+	if host.OS == nil || host.OS.Matches[0].Class.Vendor != "Linux" {
+		t.Errorf("os = %+v, want a Linux match", host.OS)
+	}
+
+	//Element order of the first host, nmap parsers read it as a stream
+	decoder := xml.NewDecoder(strings.NewReader(output))
+	var path, order []string
+	for finished := false; !finished; {
+		token, err := decoder.Token()
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			path = append(path, token.Name.Local)
+			if len(path) > 2 && path[1] == "host" {
+				order = append(order, strings.Join(path[2:], ">"))
+			}
+		case xml.EndElement:
+			path = path[:len(path)-1]
+			finished = token.Name.Local == "host"
+		}
+	}
+	want := []string{
+		"status", "address", "hostnames", "hostnames>hostname", "hostnames>hostname",
+		"ports",
+		"ports>port", "ports>port>state", "ports>port>service", "ports>port>script",
+		"ports>port", "ports>port>state", "ports>port>service", "ports>port>script", "ports>port>script",
+		"os", "os>osmatch", "os>osmatch>osclass",
+	}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("element order = %q, want %q", order, want)
+	}
+}
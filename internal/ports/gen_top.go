@@ -0,0 +1,94 @@
+//go:build ignore
+
+// gen_top writes the table of the most common TCP ports from an
+// nmap-services file, ranked by the open-frequency column.
+//
+//	go run gen_top.go -n 1000 -o top_table.go /usr/share/nmap/nmap-services
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type entry struct {
+	port      int
+	frequency float64
+}
+
+func main() {
+	count := flag.Int("n", 1000, "Amount of ports to rank")
+	out := flag.String("o", "top_table.go", "File to write")
+	flag.Parse()
+	if flag.NArg() != 1 {
+		log.Fatal("usage: go run gen_top.go [-n count] [-o file] nmap-services")
+	}
+
+	file, err := os.Open(flag.Arg(0))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+	var entries []entry
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line) //service port/protocol frequency [# comment]
+		if len(fields) < 3 || !strings.HasSuffix(fields[1], "/tcp") {
+			continue
+		}
+		port, err := strconv.Atoi(strings.TrimSuffix(fields[1], "/tcp"))
+		if err != nil {
+			continue
+		}
+		frequency, err := strconv.ParseFloat(fields[2], 64)
+		if err != nil || frequency == 0 {
+			continue
+		}
+		entries = append(entries, entry{port, frequency})
+	}
+	if err := scanner.Err(); err != nil {
+		log.Fatal(err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].frequency > entries[j].frequency
+	})
+	if len(entries) > *count {
+		entries = entries[:*count]
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen_top.go from nmap-services; DO NOT EDIT.\n\n")
+	buf.WriteString("package ports\n\n")
+	buf.WriteString("// topPorts lists TCP ports by how often they are found open in the wild,\n")
+	buf.WriteString("// most common first.\n")
+	buf.WriteString("var topPorts = []int{\n")
+	for i, e := range entries {
+		buf.WriteString(strconv.Itoa(e.port) + ",")
+		if i%20 == 19 || i == len(entries)-1 {
+			buf.WriteString("\n")
+		} else {
+			buf.WriteString(" ")
+		}
+	}
+	buf.WriteString("}\n")
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*out, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("wrote %d ports to %s\n", len(entries), *out)
+}
@@ -0,0 +1,110 @@
+package ports
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/efecankaya/go-port-scanner/data"
+)
+
+// Parse expands a comma separated port specification. Items can be port
+// numbers, ranges such as 1-1024, named sets from Sets or service names from
+// data.PortToService. Ports keep the order they are given in, without
+// duplicates.
+func Parse(spec string) ([]int, error) {
+	var port_input []int
+	seen := make(map[int]bool)
+	add := func(port int) {
+		if !seen[port] {
+			seen[port] = true
+			port_input = append(port_input, port)
+		}
+	}
+
+	for _, item := range strings.Split(spec, ",") {
+		item = strings.TrimSpace(item)
+		switch {
+		case item == "":
+			continue
+		case isNumeric(item):
+			port, err := strconv.Atoi(item)
+			if err != nil || port <= 0 || port > 65535 {
+				return nil, fmt.Errorf("port out of range ==> %s", item)
+			}
+			add(port)
+		case strings.Contains(item, "-") && isNumeric(strings.Replace(item, "-", "", 1)):
+			rangePorts := strings.Split(item, "-")
+			start_port, err1 := strconv.Atoi(rangePorts[0])
+			end_port, err2 := strconv.Atoi(rangePorts[1])
+			if err1 != nil || err2 != nil {
+				return nil, fmt.Errorf("invalid port type ==> %s", item)
+			}
+			if start_port <= 0 || end_port > 65535 || start_port > end_port {
+				return nil, fmt.Errorf("invalid range ==> %s", item)
+			}
+			for port := start_port; port <= end_port; port++ {
+				add(port)
+			}
+		default:
+			named, err := lookupName(item)
+			if err != nil {
+				return nil, err
+			}
+			for _, port := range named {
+				add(port)
+			}
+		}
+	}
+	if len(port_input) == 0 {
+		return nil, fmt.Errorf("no ports given")
+	}
+	return port_input, nil
+}
+
+// Exclude returns ports without the ones in exclude.
+func Exclude(ports []int, exclude []int) []int {
+	excluded := make(map[int]bool, len(exclude))
+	for _, port := range exclude {
+		excluded[port] = true
+	}
+	kept := make([]int, 0, len(ports))
+	for _, port := range ports {
+		if !excluded[port] {
+			kept = append(kept, port)
+		}
+	}
+	return kept
+}
+
+// lookupName resolves a named set or a service name.
+func lookupName(name string) ([]int, error) {
+	name = strings.ToLower(name)
+	if set, ok := Sets[name]; ok {
+		return set, nil
+	}
+	var named []int
+	for port, service := range data.PortToService {
+		if service == name {
+			named = append(named, port)
+		}
+	}
+	if len(named) == 0 {
+		return nil, fmt.Errorf("unknown port or service ==> %s", name)
+	}
+	sort.Ints(named)
+	return named, nil
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
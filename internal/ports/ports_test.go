@@ -0,0 +1,130 @@
+package ports
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/efecankaya/go-port-scanner/data"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec string
+		want []int
+		err  bool
+	}{
+		{spec: "80", want: []int{80}},
+		{spec: "443,80, 22", want: []int{443, 80, 22}},
+		{spec: "20-23", want: []int{20, 21, 22, 23}},
+		{spec: "65534-65535,1", want: []int{65534, 65535, 1}},
+		{spec: "22,20-23,22", want: []int{22, 20, 21, 23}},
+		{spec: "mail", want: Sets["mail"]},
+		{spec: "MAIL,25", want: Sets["mail"]},
+		{spec: "ssh,5432", want: []int{22, 5432}},
+		{spec: "postgresql", want: []int{5432}},
+		{spec: "web,db", want: append(append([]int(nil), Sets["web"]...), 1433, 1521, 3306, 5432, 5984, 6379, 7474, 8086, 9042, 11211, 27017, 27018, 28015, 50000)},
+		{spec: ",,80,", want: []int{80}},
+		{spec: "0", err: true},
+		{spec: "65536", err: true},
+		{spec: "99999999999999999999", err: true},
+		{spec: "100-10", err: true},
+		{spec: "0-10", err: true},
+		{spec: "10-65536", err: true},
+		{spec: "1-2-3", err: true},
+		{spec: "-5", err: true},
+		{spec: "no-such-service", err: true},
+		{spec: "", err: true},
+		{spec: " , ", err: true},
+	}
+	for _, test := range tests {
+		got, err := Parse(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("Parse(%q) = %v, want error", test.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", test.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Parse(%q) = %v, want %v", test.spec, got, test.want)
+		}
+	}
+}
+
+func TestParseServiceNames(t *testing.T) {
+	got, err := Parse("HTTP-ALT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var want []int
+	for port, service := range data.PortToService {
+		if service == "http-alt" {
+			want = append(want, port)
+		}
+	}
+	sort.Ints(want)
+	if len(want) < 2 || !reflect.DeepEqual(got, want) {
+		t.Errorf("Parse(HTTP-ALT) = %v, want %v", got, want)
+	}
+}
+
+func TestExclude(t *testing.T) {
+	tests := []struct {
+		ports, exclude, want []int
+	}{
+		{[]int{80, 443, 22}, []int{443}, []int{80, 22}},
+		{[]int{80, 443, 22}, []int{8080, 22, 80}, []int{443}},
+		{[]int{80, 443}, nil, []int{80, 443}},
+		{[]int{80}, []int{80}, []int{}},
+		{nil, []int{80}, []int{}},
+	}
+	for _, test := range tests {
+		if got := Exclude(test.ports, test.exclude); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("Exclude(%v, %v) = %v, want %v", test.ports, test.exclude, got, test.want)
+		}
+	}
+}
+
+func TestTop(t *testing.T) {
+	top, err := Top(5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{80, 23, 443, 21, 22}; !reflect.DeepEqual(top, want) {
+		t.Errorf("Top(5) = %v, want %v", top, want)
+	}
+	top[0] = 1 //Callers own the returned slice
+	if again, _ := Top(1); again[0] != 80 {
+		t.Error("modifying the result of Top changed the table")
+	}
+
+	all, err := Top(1000)
+	if err != nil {
+		t.Fatalf("Top(1000) error: %v", err)
+	}
+	seen := make(map[int]bool)
+	for _, port := range all {
+		if port <= 0 || port > 65535 || seen[port] {
+			t.Fatalf("Top(1000) has invalid or repeated port %d", port)
+		}
+		seen[port] = true
+	}
+	for _, port := range []int{1, 7, 3389, 8080, 49152, 65389} {
+		if !seen[port] {
+			t.Errorf("Top(1000) is missing %d", port)
+		}
+	}
+	if hundred, _ := Top(100); !reflect.DeepEqual(hundred, all[:100]) {
+		t.Error("Top(100) is not the start of Top(1000)")
+	}
+
+	for _, n := range []int{0, -1, len(topPorts) + 1, 65536} {
+		if got, err := Top(n); err == nil {
+			t.Errorf("Top(%d) = %d ports, want error", n, len(got))
+		}
+	}
+}
@@ -0,0 +1,10 @@
+package ports
+
+// Sets are the named port groups accepted by Parse.
+var Sets = map[string][]int{
+	"web":     {80, 81, 443, 591, 2082, 2083, 2086, 2087, 3000, 4443, 5000, 8000, 8008, 8080, 8081, 8088, 8443, 8888, 9000, 9090, 9200, 9443, 10443},
+	"db":      {1433, 1521, 3306, 5432, 5984, 6379, 7474, 8086, 9042, 9200, 11211, 27017, 27018, 28015, 50000},
+	"mail":    {25, 110, 143, 465, 587, 993, 995, 2525},
+	"ics":     {102, 502, 789, 1911, 1962, 2404, 4840, 5007, 9600, 18245, 20000, 44818, 47808},
+	"windows": {53, 88, 135, 137, 138, 139, 389, 445, 464, 593, 636, 3268, 3269, 3389, 5985, 5986, 47001},
+}
@@ -0,0 +1,17 @@
+package ports
+
+import (
+	"fmt"
+	"slices"
+)
+
+//go:generate go run gen_top.go -n 1000 -o top_table.go /usr/share/nmap/nmap-services
+
+// Top returns the n most common TCP ports, most common first. It fails when n
+// is larger than the ranked table instead of padding it with unranked ports.
+func Top(n int) ([]int, error) {
+	if n <= 0 || n > len(topPorts) {
+		return nil, fmt.Errorf("top ports out of range ==> %d (1-%d are ranked)", n, len(topPorts))
+	}
+	return slices.Clone(topPorts[:n]), nil
+}
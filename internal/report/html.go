@@ -0,0 +1,97 @@
+package report
+
+import (
+	_ "embed"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+//go:embed report.html.tmpl
+var htmlTemplate string
+
+var htmlPage = template.Must(template.New("report").Funcs(template.FuncMap{
+	"details":      Details,
+	"technologies": FormatTechnologies,
+	"certName":     CertificateName,
+	"join":         strings.Join,
+	"date":         func(t time.Time) string { return t.Format("2006-01-02") },
+	"time":         func(t time.Time) string { return t.Format("2006-01-02 15:04:05 MST") },
+	"before":       func(a, b time.Time) bool { return a.Before(b) },
+}).Parse(htmlTemplate))
+
+// Overview describes the scan a report is made of.
+type Overview struct {
+	StartTime   time.Time //Time the scan was started
+	EndTime     time.Time //Time the scan was finished
+	Targets     []string  //Target specifications of the scan
+	Interrupted bool      //Scan was stopped before finishing
+	HostsUp     int       //Hosts found alive by discovery
+	HostsDown   int       //Hosts skipped by discovery
+}
+
+// Count is a value and how many times it was seen.
+type Count struct {
+	Name  string
+	Count int
+	Share int //Percentage of the largest count, for bar widths
+}
+
+// page is the data the HTML template renders.
+type page struct {
+	Overview
+	Hosts        []Host
+	OpenPorts    int
+	Services     []Count //Open ports by service
+	Technologies []Count //Hosts by technology
+	Systems      []Count //Hosts by operating system family
+}
+
+// WriteHTML renders hosts as a single HTML document with its styles inline,
+// so it opens offline: a dashboard of counts followed by a section per host.
+func WriteHTML(w io.Writer, hosts []Host, overview Overview) error {
+	data := page{Overview: overview, Hosts: hosts}
+	services := make(map[string]int)
+	technologies := make(map[string]int)
+	systems := make(map[string]int)
+	for _, host := range hosts {
+		data.OpenPorts += host.OpenPorts()
+		for _, port := range host.Ports {
+			if port.State == scanner.StateOpen {
+				services[port.ServiceName()]++
+			}
+		}
+		for _, tech := range host.Technologies {
+			technologies[tech.Name]++
+		}
+		if host.OS != nil {
+			systems[host.OS.Family]++
+		}
+	}
+	data.Services = counts(services)
+	data.Technologies = counts(technologies)
+	data.Systems = counts(systems)
+	return htmlPage.Execute(w, data)
+}
+
+// counts sorts a tally by count, then name.
+func counts(tally map[string]int) []Count {
+	list := make([]Count, 0, len(tally))
+	for name, count := range tally {
+		list = append(list, Count{Name: name, Count: count})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Name < list[j].Name
+	})
+	for i := range list {
+		list[i].Share = list[i].Count * 100 / list[0].Count
+	}
+	return list
+}
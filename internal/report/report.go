@@ -0,0 +1,212 @@
+// Package report regroups the results of a scan, which arrive one port at a
+// time in no particular order, into one entry per host with its ports sorted
+// and its service, operating system and technology findings merged.
+package report
+
+import (
+	"net/netip"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/efecankaya/go-port-scanner/scanner"
+)
+
+// Host is everything found on a single address.
+type Host struct {
+	IP              string               //Address of the host
+	Hostnames       []string             //Domain names that resolved to IP
+	OperatingSystem string               //Operating system guess, empty if nothing hinted at one
+	OS              *scanner.OSGuess     //Details of the guess, nil when only the string is known
+	Ports           []Port               //Ports by protocol then number
+	Technologies    []scanner.Technology //Technologies of every port, sorted by name
+}
+
+// Port is the result of a host:port pair along with the requests made on it
+// for other host names.
+type Port struct {
+	scanner.TargetResult
+	VHosts []scanner.TargetResult //Results of the other host names, in scan order
+}
+
+// Build groups results by host. systems holds the operating system guessed
+// per host once the scan is over, it takes precedence over the guesses the
+// results carry.
+func Build(results []scanner.TargetResult, systems map[string]scanner.OSGuess) []Host {
+	hosts := make(map[string]*Host)
+	ports := make(map[string]map[string]int) //Index in Ports by host then protocol/port
+	for _, result := range results {
+		host, ok := hosts[result.HostIP]
+		if !ok {
+			host = &Host{IP: result.HostIP}
+			hosts[result.HostIP] = host
+			ports[result.HostIP] = make(map[string]int)
+		}
+		for _, name := range result.Hostnames {
+			if !contains(host.Hostnames, name) {
+				host.Hostnames = append(host.Hostnames, name)
+			}
+		}
+		if result.OperatingSystem != "" {
+			host.OperatingSystem = result.OperatingSystem //Later guesses saw more ports
+		}
+		host.Technologies = mergeTechnologies(host.Technologies, result.Technologies)
+
+		key := result.Protocol + "/" + strconv.Itoa(result.Port)
+		if i, ok := ports[result.HostIP][key]; ok { //The first result of a port is the primary one
+			host.Ports[i].VHosts = append(host.Ports[i].VHosts, result)
+			continue
+		}
+		ports[result.HostIP][key] = len(host.Ports)
+		host.Ports = append(host.Ports, Port{TargetResult: result})
+	}
+
+	list := make([]Host, 0, len(hosts))
+	for ip, host := range hosts {
+		if guess, ok := systems[ip]; ok {
+			host.OS = &guess
+			host.OperatingSystem = guess.String()
+		}
+		sort.SliceStable(host.Ports, func(i, j int) bool {
+			if host.Ports[i].Protocol != host.Ports[j].Protocol {
+				return host.Ports[i].Protocol < host.Ports[j].Protocol
+			}
+			return host.Ports[i].Port < host.Ports[j].Port
+		})
+		list = append(list, *host)
+	}
+	sort.Slice(list, func(i, j int) bool { return lessAddress(list[i].IP, list[j].IP) })
+	return list
+}
+
+// OpenPorts counts the open ports of h.
+func (h Host) OpenPorts() int {
+	count := 0
+	for _, port := range h.Ports {
+		if port.State == scanner.StateOpen {
+			count++
+		}
+	}
+	return count
+}
+
+// ServiceName is the identified service of p, "unknown" without one.
+func (p Port) ServiceName() string {
+	if p.Service == nil || p.Service.Name == "" {
+		return "unknown"
+	}
+	return p.Service.Name
+}
+
+// Version is the product and version identified on p.
+func (p Port) Version() string {
+	if p.Service == nil {
+		return ""
+	}
+	return strings.TrimSpace(p.Service.Product + " " + p.Service.Version)
+}
+
+// Details summarizes the HTTP and TLS findings of a result in one line. The
+// banner stands in when neither was found and no product was identified.
+func Details(result scanner.TargetResult) string {
+	var parts []string
+	if result.TLS != nil {
+		tls := result.TLS.Version
+		if len(result.TLS.Certificates) > 0 {
+			tls += " " + CertificateName(result.TLS.Certificates[0])
+		}
+		parts = append(parts, tls)
+	}
+	if result.HttpValid {
+		http := strconv.Itoa(result.HttpStatusCode)
+		if result.HttpTitle != "" {
+			http += " \"" + result.HttpTitle + "\""
+		}
+		parts = append(parts, http)
+	}
+	if len(parts) == 0 && (result.Service == nil || result.Service.Product == "") && result.Banner != "" {
+		parts = append(parts, truncate(result.Banner, 60))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// CertificateName is the name a certificate was issued for: its first SAN,
+// or the common name of its subject.
+func CertificateName(cert scanner.CertificateInfo) string {
+	if len(cert.SANs) > 0 {
+		return cert.SANs[0]
+	}
+	for _, part := range strings.Split(cert.Subject, ",") {
+		if name, ok := strings.CutPrefix(strings.TrimSpace(part), "CN="); ok {
+			return name
+		}
+	}
+	return cert.Subject
+}
+
+// FormatTechnologies renders technologies as a single line such as
+// "Nginx 1.25.3 (Web servers), PHP (Programming languages)".
+func FormatTechnologies(technologies []scanner.Technology) string {
+	names := make([]string, 0, len(technologies))
+	for _, tech := range technologies {
+		name := tech.Name
+		if tech.Version != "" {
+			name += " " + tech.Version
+		}
+		if len(tech.Categories) > 0 {
+			name += " (" + strings.Join(tech.Categories, ", ") + ")"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// mergeTechnologies adds found to known, keeping the most precise version and
+// the highest confidence of technologies seen on several ports.
+func mergeTechnologies(known, found []scanner.Technology) []scanner.Technology {
+	for _, tech := range found {
+		i := sort.Search(len(known), func(i int) bool { return known[i].Name >= tech.Name })
+		if i < len(known) && known[i].Name == tech.Name {
+			if len(tech.Version) > len(known[i].Version) {
+				known[i].Version = tech.Version
+			}
+			known[i].Confidence = max(known[i].Confidence, tech.Confidence)
+			continue
+		}
+		known = append(known, scanner.Technology{})
+		copy(known[i+1:], known[i:])
+		known[i] = tech
+	}
+	return known
+}
+
+// lessAddress orders IP addresses numerically, IPv4 first, and anything else
+// after them alphabetically.
+func lessAddress(a, b string) bool {
+	addr_a, err_a := netip.ParseAddr(a)
+	addr_b, err_b := netip.ParseAddr(b)
+	switch {
+	case err_a == nil && err_b == nil:
+		return addr_a.Less(addr_b)
+	case err_a == nil || err_b == nil:
+		return err_a == nil
+	}
+	return a < b
+}
+
+func contains(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func truncate(text string, length int) string {
+	runes := []rune(text)
+	if len(runes) <= length {
+		return text
+	}
+	return string(runes[:length]) + "..."
+}
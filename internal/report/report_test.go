@@ -0,0 +1,242 @@
+package report
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/efecankaya/go-port-scanner/scanner"
+	"golang.org/x/net/html"
+)
+
+// fixtureResults is a scan of two hosts, one serving a page that tries to
+// pull in remote resources through every field the report shows.
+func fixtureResults() []scanner.TargetResult {
+	expiry := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []scanner.TargetResult{
+		{
+			HostIP:    "10.0.0.2",
+			Hostnames: []string{"www.example.com"},
+			Hostname:  "www.example.com",
+			Port:      443,
+			Protocol:  "tcp",
+			State:     scanner.StateOpen,
+			Service:   &scanner.ServiceInfo{Name: "https", Product: "nginx", Version: "1.25.3", Confidence: 90, Method: "probe"},
+			TLS: &scanner.TLSInfo{Version: "TLS 1.3", CipherSuite: "TLS_AES_128_GCM_SHA256", Certificates: []scanner.CertificateInfo{{
+				Subject:  "CN=www.example.com",
+				SANs:     []string{"www.example.com", "example.com"},
+				Issuer:   "CN=Example CA",
+				NotAfter: expiry,
+			}}},
+			HttpValid:      true,
+			HttpStatusCode: 200,
+			HttpTitle:      `<script src="https://evil.example/x.js"></script><img src=//evil.example/p.png>`,
+			Favicon:        &scanner.FaviconInfo{URL: "https://cdn.example.net/favicon.ico", MMH3: 116323821},
+			Technologies:   []scanner.Technology{{Name: "Nginx", Version: "1.25.3", Confidence: 100}},
+		},
+		{
+			HostIP:    "10.0.0.2",
+			Hostname:  "admin.example.com",
+			Port:      443,
+			Protocol:  "tcp",
+			State:     scanner.StateOpen,
+			HttpValid: true, HttpStatusCode: 302,
+			HttpTitle: `<a href="http://evil.example/">admin</a>`,
+		},
+		{
+			HostIP:   "10.0.0.2",
+			Port:     22,
+			Protocol: "tcp",
+			State:    scanner.StateOpen,
+			Banner:   `SSH-2.0-OpenSSH_9.6 <link href="https://evil.example/a.css">`,
+			Service:  &scanner.ServiceInfo{Name: "ssh", Product: "OpenSSH", Version: "9.6"},
+		},
+		{
+			HostIP:   "10.0.0.10",
+			Port:     161,
+			Protocol: "udp",
+			State:    scanner.StateOpenFiltered,
+		},
+		{
+			HostIP:   "10.0.0.2",
+			Port:     80,
+			Protocol: "tcp",
+			State:    scanner.StateClosed,
+		},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	systems := map[string]scanner.OSGuess{"10.0.0.2": {Family: "Linux", Confidence: 80}}
+	hosts := Build(fixtureResults(), systems)
+	if len(hosts) != 2 || hosts[0].IP != "10.0.0.2" || hosts[1].IP != "10.0.0.10" {
+		t.Fatalf("hosts = %+v, want 10.0.0.2 then 10.0.0.10", hosts)
+	}
+	host := hosts[0]
+	var ports []int
+	for _, port := range host.Ports {
+		ports = append(ports, port.Port)
+	}
+	if want := []int{22, 80, 443}; !equalInts(ports, want) {
+		t.Errorf("ports = %v, want %v", ports, want)
+	}
+	if vhosts := host.Ports[2].VHosts; len(vhosts) != 1 || vhosts[0].Hostname != "admin.example.com" {
+		t.Errorf("vhosts of 443 = %+v, want admin.example.com", vhosts)
+	}
+	if host.OpenPorts() != 2 {
+		t.Errorf("OpenPorts() = %d, want 2", host.OpenPorts())
+	}
+	if host.OS == nil || host.OS.Family != "Linux" {
+		t.Errorf("OS = %+v, want the guess from systems", host.OS)
+	}
+	if len(host.Technologies) != 1 || host.Technologies[0].Name != "Nginx" {
+		t.Errorf("technologies = %+v", host.Technologies)
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// externalRefs lists the src and href attributes of the page that load or
+// link anything outside it.
+func externalRefs(t *testing.T, page string) []string {
+	t.Helper()
+	var refs []string
+	tokenizer := html.NewTokenizer(strings.NewReader(page))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if tokenizer.Err() != io.EOF {
+				t.Fatal(tokenizer.Err())
+			}
+			return refs
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			for _, attr := range token.Attr {
+				value := strings.TrimSpace(attr.Val)
+				if (attr.Key == "src" || attr.Key == "href") && (strings.HasPrefix(value, "//") || strings.Contains(value, ":")) {
+					refs = append(refs, token.Data+" "+attr.Key+"="+value)
+				}
+			}
+		}
+	}
+}
+
+func TestWriteHTML(t *testing.T) {
+	start := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+	overview := Overview{StartTime: start, EndTime: start.Add(time.Minute), Targets: []string{"10.0.0.0/28"}, HostsUp: 2, HostsDown: 12}
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, Build(fixtureResults(), nil), overview); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+	if refs := externalRefs(t, page); len(refs) > 0 {
+		t.Errorf("report references external resources: %q", refs)
+	}
+	for _, want := range []string{
+		"10.0.0.2", "10.0.0.10", "www.example.com", "admin.example.com",
+		"OpenSSH 9.6", "nginx 1.25.3", "Issuer: CN=Example CA", "Nginx",
+		"&lt;script src=&#34;https://evil.example/x.js&#34;&gt;",
+		"10.0.0.0/28",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("report is missing %q", want)
+		}
+	}
+	if strings.Contains(page, "<script src") || strings.Contains(page, "<img") {
+		t.Error("scanned content was not escaped")
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTable(&buf, Build(fixtureResults(), nil)); err != nil {
+		t.Fatal(err)
+	}
+	table := buf.String()
+	for _, want := range []string{"10.0.0.2", "www.example.com", "22/tcp", "443/tcp", "ssh", "OpenSSH 9.6", "161/udp", "open|filtered"} {
+		if !strings.Contains(table, want) {
+			t.Errorf("table is missing %q:\n%s", want, table)
+		}
+	}
+}
+
+func TestDetails(t *testing.T) {
+	cert := scanner.CertificateInfo{Subject: "CN=www.example.com,O=Example"}
+	tests := []struct {
+		result scanner.TargetResult
+		want   string
+	}{
+		{scanner.TargetResult{}, ""},
+		{scanner.TargetResult{Banner: "220 ready"}, "220 ready"},
+		{scanner.TargetResult{Banner: strings.Repeat("é", 70)}, strings.Repeat("é", 60) + "..."},
+		{scanner.TargetResult{Banner: "SSH-2.0-OpenSSH_9.6", Service: &scanner.ServiceInfo{Name: "ssh", Product: "OpenSSH"}}, ""},
+		{scanner.TargetResult{HttpValid: true, HttpStatusCode: 404}, "404"},
+		{scanner.TargetResult{HttpValid: true, HttpStatusCode: 200, HttpTitle: "Home", Banner: "ignored"}, `200 "Home"`},
+		{scanner.TargetResult{TLS: &scanner.TLSInfo{Version: "TLS 1.2"}}, "TLS 1.2"},
+		{scanner.TargetResult{TLS: &scanner.TLSInfo{Version: "TLS 1.3", Certificates: []scanner.CertificateInfo{cert}}, HttpValid: true, HttpStatusCode: 301}, "TLS 1.3 www.example.com, 301"},
+	}
+	for _, test := range tests {
+		if got := Details(test.result); got != test.want {
+			t.Errorf("Details(%+v) = %q, want %q", test.result, got, test.want)
+		}
+	}
+}
+
+func TestCertificateName(t *testing.T) {
+	tests := map[string]scanner.CertificateInfo{
+		"a.example.com":  {Subject: "CN=b.example.com", SANs: []string{"a.example.com", "b.example.com"}},
+		"b.example.com":  {Subject: "O=Example, CN=b.example.com"},
+		"O=Example,C=US": {Subject: "O=Example,C=US"},
+		"":               {},
+	}
+	for want, cert := range tests {
+		if got := CertificateName(cert); got != want {
+			t.Errorf("CertificateName(%+v) = %q, want %q", cert, got, want)
+		}
+	}
+}
+
+func TestMergeTechnologies(t *testing.T) {
+	var known []scanner.Technology
+	known = mergeTechnologies(known, []scanner.Technology{{Name: "PHP", Confidence: 50}, {Name: "Nginx", Version: "1.25", Confidence: 100}})
+	known = mergeTechnologies(known, []scanner.Technology{{Name: "PHP", Version: "8.2.1", Confidence: 80}, {Name: "Nginx", Confidence: 40}, {Name: "jQuery"}})
+	want := []scanner.Technology{
+		{Name: "Nginx", Version: "1.25", Confidence: 100},
+		{Name: "PHP", Version: "8.2.1", Confidence: 80},
+		{Name: "jQuery"},
+	}
+	if len(known) != len(want) {
+		t.Fatalf("merged = %+v, want %+v", known, want)
+	}
+	for i := range want {
+		if known[i].Name != want[i].Name || known[i].Version != want[i].Version || known[i].Confidence != want[i].Confidence {
+			t.Errorf("merged[%d] = %+v, want %+v", i, known[i], want[i])
+		}
+	}
+	if got := FormatTechnologies([]scanner.Technology{{Name: "Nginx", Version: "1.25", Categories: []string{"Web servers", "Reverse proxies"}}, {Name: "PHP"}}); got != "Nginx 1.25 (Web servers, Reverse proxies), PHP" {
+		t.Errorf("FormatTechnologies = %q", got)
+	}
+}
+
+func TestLessAddress(t *testing.T) {
+	sorted := []string{"9.9.9.9", "10.0.0.2", "10.0.0.10", "2001:db8::1", "2001:db8::a", "a.example.com", "b.example.com"}
+	for i := range sorted {
+		for j := range sorted {
+			if got := lessAddress(sorted[i], sorted[j]); got != (i < j) {
+				t.Errorf("lessAddress(%s, %s) = %v, want %v", sorted[i], sorted[j], got, i < j)
+			}
+		}
+	}
+}
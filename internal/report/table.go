@@ -0,0 +1,59 @@
+package report
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// WriteTable renders hosts for the terminal: a heading per host followed by
+// an aligned table of its ports and the technologies found on them.
+func WriteTable(w io.Writer, hosts []Host) error {
+	for i, host := range hosts {
+		if i > 0 {
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+		if err := writeHost(w, host); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeHost(w io.Writer, host Host) error {
+	heading := host.IP
+	if len(host.Hostnames) > 0 {
+		heading += " (" + strings.Join(host.Hostnames, ", ") + ")"
+	}
+	if _, err := fmt.Fprintln(w, heading); err != nil {
+		return err
+	}
+	if host.OperatingSystem != "" {
+		if _, err := fmt.Fprintf(w, "OS: %s\n", host.OperatingSystem); err != nil {
+			return err
+		}
+	}
+
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PORT\tSTATE\tSERVICE\tVERSION\tDETAILS")
+	for _, port := range host.Ports {
+		fmt.Fprintf(table, "%s/%s\t%s\t%s\t%s\t%s\n", strconv.Itoa(port.Port), port.Protocol, port.State, port.ServiceName(), port.Version(), Details(port.TargetResult))
+		for _, vhost := range port.VHosts {
+			fmt.Fprintf(table, "\t\t\t\t%s: %s\n", vhost.Hostname, Details(vhost))
+		}
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+
+	if len(host.Technologies) > 0 {
+		if _, err := fmt.Fprintf(w, "Technologies: %s\n", FormatTechnologies(host.Technologies)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
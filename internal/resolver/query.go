@@ -0,0 +1,136 @@
+package resolver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+const (
+	typeA    = uint16(dnsmessage.TypeA)
+	typeAAAA = uint16(dnsmessage.TypeAAAA)
+)
+
+// records holds the useful part of a DNS response, keyed by lower case names
+// without the trailing dot.
+type records struct {
+	cnames    map[string]string   //Name to CNAME target
+	addresses map[string][]string //Name to its addresses
+}
+
+// query sends a single question to the server over UDP, retrying over TCP
+// when the response is truncated.
+func (r *Resolver) query(ctx context.Context, name string, qtype uint16) (records, error) {
+	id := uint16(rand.Intn(1 << 16))
+	fqdn, err := dnsmessage.NewName(name + ".")
+	if err != nil {
+		return records{}, err
+	}
+	message := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: id, RecursionDesired: true},
+		Questions: []dnsmessage.Question{{Name: fqdn, Type: dnsmessage.Type(qtype), Class: dnsmessage.ClassINET}},
+	}
+	packet, err := message.Pack()
+	if err != nil {
+		return records{}, err
+	}
+
+	response, err := r.exchange(ctx, "udp", packet, id)
+	if err == nil && response.Truncated {
+		response, err = r.exchange(ctx, "tcp", packet, id)
+	}
+	if err != nil {
+		return records{}, err
+	}
+	switch response.RCode {
+	case dnsmessage.RCodeSuccess:
+	case dnsmessage.RCodeNameError:
+		return records{}, ErrNotFound
+	default:
+		return records{}, fmt.Errorf("server answered %s", response.RCode)
+	}
+	return parseRecords(response), nil
+}
+
+// exchange sends packet to the server over network and returns the response
+// matching id.
+func (r *Resolver) exchange(ctx context.Context, network string, packet []byte, id uint16) (*dnsmessage.Message, error) {
+	dialer := net.Dialer{Timeout: r.timeout}
+	conn, err := dialer.DialContext(ctx, network, r.server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(r.timeout))
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	if network == "tcp" { //TCP messages carry a length prefix
+		packet = append(binary.BigEndian.AppendUint16(nil, uint16(len(packet))), packet...)
+	}
+	if _, err := conn.Write(packet); err != nil {
+		return nil, err
+	}
+	for {
+		var buf []byte
+		if network == "tcp" {
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err != nil {
+				return nil, err
+			}
+			buf = make([]byte, binary.BigEndian.Uint16(length[:]))
+			if _, err := io.ReadFull(conn, buf); err != nil {
+				return nil, err
+			}
+		} else {
+			buf = make([]byte, 65535)
+			n, err := conn.Read(buf)
+			if err != nil {
+				return nil, err
+			}
+			buf = buf[:n]
+		}
+		var response dnsmessage.Message
+		if err := response.Unpack(buf); err != nil {
+			if network == "tcp" {
+				return nil, err
+			}
+			continue //Garbage on a UDP socket, keep waiting
+		}
+		if response.ID != id || !response.Response {
+			if network == "tcp" {
+				return nil, errors.New("mismatched DNS response")
+			}
+			continue
+		}
+		return &response, nil
+	}
+}
+
+func parseRecords(response *dnsmessage.Message) records {
+	result := records{cnames: make(map[string]string), addresses: make(map[string][]string)}
+	for _, answer := range response.Answers {
+		name := normalize(answer.Header.Name.String())
+		switch body := answer.Body.(type) {
+		case *dnsmessage.CNAMEResource:
+			result.cnames[name] = normalize(body.CNAME.String())
+		case *dnsmessage.AResource:
+			result.addresses[name] = append(result.addresses[name], net.IP(body.A[:]).String())
+		case *dnsmessage.AAAAResource:
+			result.addresses[name] = append(result.addresses[name], net.IP(body.AAAA[:]).String())
+		}
+	}
+	return result
+}
+
+func normalize(name string) string {
+	return strings.ToLower(strings.TrimSuffix(name, "."))
+}
@@ -0,0 +1,278 @@
+// Package resolver looks up the addresses of domain names, either through the
+// system resolver or by querying a given DNS server directly. Lookups run on a
+// pool of workers and their answers are cached for the life of the Resolver.
+package resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+const maxCNAMEs = 8 //CNAME hops followed before giving up
+
+var (
+	ErrNotFound  = errors.New("no such host")
+	ErrCNAMELoop = errors.New("too many CNAME hops")
+)
+
+// Answer is the outcome of looking up a single name.
+type Answer struct {
+	Name      string   //Name that was looked up
+	CNAMEs    []string //CNAME chain followed from Name, in order
+	Addresses []string //IPv4 and IPv6 addresses of the final name
+	Err       error    //Why no address was found
+}
+
+// Resolver looks up names. It is safe for concurrent use.
+type Resolver struct {
+	server  string //DNS server as host:port, empty for the system resolver
+	ipv4    bool   //Look up A records
+	ipv6    bool   //Look up AAAA records
+	timeout time.Duration
+	workers int
+
+	mu    sync.Mutex
+	cache map[string]*cacheEntry
+}
+
+type cacheEntry struct {
+	done   chan struct{} //Closed once answer is set
+	answer Answer
+}
+
+type Option func(*Resolver)
+
+// WithServer sends queries straight to a DNS server instead of using the
+// system resolver. A missing port defaults to 53.
+func WithServer(server string) Option {
+	return func(r *Resolver) {
+		if server != "" {
+			if _, _, err := net.SplitHostPort(server); err != nil {
+				server = net.JoinHostPort(server, "53")
+			}
+		}
+		r.server = server
+	}
+}
+
+// WithRecordTypes selects whether A and AAAA records are looked up.
+func WithRecordTypes(ipv4, ipv6 bool) Option {
+	return func(r *Resolver) { r.ipv4, r.ipv6 = ipv4, ipv6 }
+}
+
+// WithTimeout sets the timeout of every query.
+func WithTimeout(timeout time.Duration) Option {
+	return func(r *Resolver) { r.timeout = timeout }
+}
+
+// WithWorkers sets the number of names LookupAll resolves at once.
+func WithWorkers(n int) Option {
+	return func(r *Resolver) { r.workers = n }
+}
+
+// New creates a Resolver. Without options it uses the system resolver, looks
+// up both A and AAAA records with a two second timeout and 50 workers.
+func New(options ...Option) (*Resolver, error) {
+	r := &Resolver{
+		ipv4:    true,
+		ipv6:    true,
+		timeout: 2 * time.Second,
+		workers: 50,
+		cache:   make(map[string]*cacheEntry),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	if !r.ipv4 && !r.ipv6 {
+		return nil, errors.New("no record type selected")
+	}
+	if r.workers <= 0 {
+		return nil, errors.New("resolver workers must be positive")
+	}
+	if r.timeout <= 0 {
+		return nil, errors.New("resolver timeout must be positive")
+	}
+	return r, nil
+}
+
+// Lookup resolves name, following CNAME records. Answers, failures included,
+// are cached so every name is only queried once.
+func (r *Resolver) Lookup(ctx context.Context, name string) Answer {
+	key := strings.ToLower(strings.TrimSuffix(name, "."))
+	r.mu.Lock()
+	entry, ok := r.cache[key]
+	if !ok {
+		entry = &cacheEntry{done: make(chan struct{})}
+		r.cache[key] = entry
+	}
+	r.mu.Unlock()
+	if ok {
+		select {
+		case <-entry.done:
+			return entry.answer
+		case <-ctx.Done():
+			return Answer{Name: name, Err: ctx.Err()}
+		}
+	}
+
+	if r.server == "" {
+		entry.answer = r.lookupSystem(ctx, key)
+	} else {
+		entry.answer = r.lookupServer(ctx, key)
+	}
+	entry.answer.Name = key
+	if ctx.Err() != nil { //Cancelled lookups are not cached
+		r.mu.Lock()
+		delete(r.cache, key)
+		r.mu.Unlock()
+	}
+	close(entry.done)
+	return entry.answer
+}
+
+// LookupAll resolves names on the worker pool and returns their answers in
+// the order of names.
+func (r *Resolver) LookupAll(ctx context.Context, names []string) []Answer {
+	answers := make([]Answer, len(names))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < r.workers && i < len(names); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				answers[i] = r.Lookup(ctx, names[i])
+			}
+		}()
+	}
+	for i := range names {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+	return answers
+}
+
+// lookupSystem resolves name through the resolver of the operating system,
+// which only reveals the final name of a CNAME chain.
+func (r *Resolver) lookupSystem(ctx context.Context, name string) Answer {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	network := "ip"
+	if !r.ipv6 {
+		network = "ip4"
+	} else if !r.ipv4 {
+		network = "ip6"
+	}
+	var answer Answer
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, name)
+	if err != nil {
+		answer.Err = err
+		return answer
+	}
+	for _, ip := range ips {
+		answer.Addresses = append(answer.Addresses, ip.String())
+	}
+	if cname, err := net.DefaultResolver.LookupCNAME(ctx, name); err == nil {
+		if cname = strings.TrimSuffix(cname, "."); !strings.EqualFold(cname, name) {
+			answer.CNAMEs = []string{strings.ToLower(cname)}
+		}
+	}
+	return answer
+}
+
+// lookupServer queries the configured server for each selected record type
+// and follows the CNAME chain until a name with addresses is reached.
+func (r *Resolver) lookupServer(ctx context.Context, name string) Answer {
+	var answer Answer
+	var types []uint16
+	if r.ipv4 {
+		types = append(types, typeA)
+	}
+	if r.ipv6 {
+		types = append(types, typeAAAA)
+	}
+	current := name
+	for hop := 0; ; hop++ {
+		if hop > maxCNAMEs {
+			answer.Err = ErrCNAMELoop
+			return answer
+		}
+		var next string
+		for _, qtype := range types {
+			records, err := r.query(ctx, current, qtype)
+			if err != nil {
+				answer.Err = err
+				continue
+			}
+			final, chain := followChain(current, records)
+			answer.CNAMEs = mergeChain(answer.CNAMEs, chain)
+			addresses := records.addresses[final]
+			if len(addresses) == 0 && final != current {
+				next = final //Server left the chain unresolved
+			}
+			answer.Addresses = append(answer.Addresses, addresses...)
+		}
+		if len(answer.Addresses) > 0 {
+			answer.Err = nil
+			return answer
+		}
+		if next == "" {
+			if answer.Err == nil {
+				answer.Err = ErrNotFound
+			}
+			answer.Err = fmt.Errorf("lookup %s: %w", name, answer.Err)
+			return answer
+		}
+		current = next
+	}
+}
+
+// followChain walks the CNAME records from name and returns the final name
+// along with the hops taken.
+func followChain(name string, records records) (string, []string) {
+	var chain []string
+	for len(chain) <= maxCNAMEs {
+		target, ok := records.cnames[name]
+		if !ok {
+			break
+		}
+		chain = append(chain, target)
+		name = target
+	}
+	return name, chain
+}
+
+// mergeChain appends the names of chain missing from known, keeping order.
+func mergeChain(known, chain []string) []string {
+	for _, name := range chain {
+		found := false
+		for _, k := range known {
+			found = found || k == name
+		}
+		if !found {
+			known = append(known, name)
+		}
+	}
+	return known
+}
+
+// Hosts maps every address of answers to the names that resolved to it, once
+// each and in the order of answers.
+func Hosts(answers []Answer) map[string][]string {
+	hosts := make(map[string][]string)
+	for _, answer := range answers {
+		for _, address := range answer.Addresses {
+			if !slices.Contains(hosts[address], answer.Name) {
+				hosts[address] = append(hosts[address], answer.Name)
+			}
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,290 @@
+package resolver
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/netip"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// zoneRecord is what the stub server knows about a name.
+type zoneRecord struct {
+	cname string   //CNAME target, answered alone so the client follows it
+	a     []string //IPv4 addresses
+	aaaa  []string //IPv6 addresses
+	chain bool     //Answer the whole CNAME chain at once like a recursive server
+	big   bool     //Truncate UDP answers, the records only come over TCP
+}
+
+// stubServer answers queries for a fixed zone over UDP and TCP on the same
+// loopback port.
+type stubServer struct {
+	addr    string
+	zone    map[string]zoneRecord
+	mu      sync.Mutex
+	queries map[string]int //Queries received by network/name/type
+}
+
+func newStubServer(t *testing.T, zone map[string]zoneRecord) *stubServer {
+	t.Helper()
+	var (
+		udp *net.UDPConn
+		tcp net.Listener
+		err error
+	)
+	for attempt := 0; attempt < 10; attempt++ { //The UDP port may be taken over TCP
+		if udp, err = net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)}); err != nil {
+			t.Fatal(err)
+		}
+		if tcp, err = net.Listen("tcp", udp.LocalAddr().String()); err == nil {
+			break
+		}
+		udp.Close()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stubServer{addr: udp.LocalAddr().String(), zone: zone, queries: make(map[string]int)}
+	t.Cleanup(func() {
+		udp.Close()
+		tcp.Close()
+	})
+
+	go func() {
+		buf := make([]byte, 65535)
+		for {
+			n, from, err := udp.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if response := s.answer(buf[:n], "udp"); response != nil {
+				udp.WriteToUDP(response, from)
+			}
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				var length [2]byte
+				if _, err := io.ReadFull(conn, length[:]); err != nil {
+					return
+				}
+				packet := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, packet); err != nil {
+					return
+				}
+				if response := s.answer(packet, "tcp"); response != nil {
+					conn.Write(append(binary.BigEndian.AppendUint16(nil, uint16(len(response))), response...))
+				}
+			}()
+		}
+	}()
+	return s
+}
+
+func (s *stubServer) count(network, name string, qtype dnsmessage.Type) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.queries[network+"/"+name+"/"+qtype.String()]
+}
+
+func (s *stubServer) answer(packet []byte, network string) []byte {
+	var query dnsmessage.Message
+	if err := query.Unpack(packet); err != nil || len(query.Questions) != 1 {
+		return nil
+	}
+	question := query.Questions[0]
+	name := normalize(question.Name.String())
+	s.mu.Lock()
+	s.queries[network+"/"+name+"/"+question.Type.String()]++
+	s.mu.Unlock()
+
+	response := dnsmessage.Message{
+		Header:    dnsmessage.Header{ID: query.ID, Response: true, RecursionAvailable: true},
+		Questions: query.Questions,
+	}
+	record, ok := s.zone[name]
+	if !ok {
+		response.RCode = dnsmessage.RCodeNameError
+	} else if record.big && network == "udp" {
+		response.Truncated = true
+	} else {
+		current := name
+		for hops := 0; ok && hops <= maxCNAMEs+2; hops++ {
+			header := dnsmessage.ResourceHeader{Name: dnsmessage.MustNewName(current + "."), Class: dnsmessage.ClassINET, TTL: 60}
+			if record.cname != "" {
+				response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.CNAMEResource{CNAME: dnsmessage.MustNewName(record.cname + ".")}})
+				if !record.chain {
+					break
+				}
+				current = record.cname
+				record, ok = s.zone[current]
+				continue
+			}
+			if question.Type == dnsmessage.TypeA {
+				for _, address := range record.a {
+					response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AResource{A: netip.MustParseAddr(address).As4()}})
+				}
+			}
+			if question.Type == dnsmessage.TypeAAAA {
+				for _, address := range record.aaaa {
+					response.Answers = append(response.Answers, dnsmessage.Resource{Header: header, Body: &dnsmessage.AAAAResource{AAAA: netip.MustParseAddr(address).As16()}})
+				}
+			}
+			break
+		}
+	}
+	packed, err := response.Pack()
+	if err != nil {
+		return nil
+	}
+	return packed
+}
+
+var testZone = map[string]zoneRecord{
+	"host.test":     {a: []string{"192.0.2.1"}},
+	"dual.test":     {a: []string{"192.0.2.2"}, aaaa: []string{"2001:db8::2"}},
+	"v6only.test":   {aaaa: []string{"2001:db8::3"}},
+	"alias.test":    {cname: "mid.test"},
+	"mid.test":      {cname: "host.test"},
+	"full.test":     {cname: "mid.test", chain: true},
+	"dangling.test": {cname: "missing.test"},
+	"loop1.test":    {cname: "loop2.test"},
+	"loop2.test":    {cname: "loop1.test"},
+	"big.test":      {a: []string{"192.0.2.10", "192.0.2.11"}, big: true},
+}
+
+func newTestResolver(t *testing.T, server *stubServer, options ...Option) *Resolver {
+	t.Helper()
+	options = append([]Option{WithServer(server.addr), WithTimeout(time.Second)}, options...)
+	r, err := New(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestLookupServer(t *testing.T) {
+	server := newStubServer(t, testZone)
+	tests := []struct {
+		name      string
+		ipv4      bool
+		ipv6      bool
+		cnames    []string
+		addresses []string
+		err       error
+	}{
+		{name: "host.test", ipv4: true, ipv6: true, addresses: []string{"192.0.2.1"}},
+		{name: "HOST.test.", ipv4: true, ipv6: true, addresses: []string{"192.0.2.1"}},
+		{name: "dual.test", ipv4: true, ipv6: true, addresses: []string{"192.0.2.2", "2001:db8::2"}},
+		{name: "dual.test", ipv4: true, addresses: []string{"192.0.2.2"}},
+		{name: "dual.test", ipv6: true, addresses: []string{"2001:db8::2"}},
+		{name: "v6only.test", ipv4: true, err: ErrNotFound},
+		{name: "alias.test", ipv4: true, ipv6: true, cnames: []string{"mid.test", "host.test"}, addresses: []string{"192.0.2.1"}},
+		{name: "full.test", ipv4: true, cnames: []string{"mid.test", "host.test"}, addresses: []string{"192.0.2.1"}},
+		{name: "dangling.test", ipv4: true, cnames: []string{"missing.test"}, err: ErrNotFound},
+		{name: "loop1.test", ipv4: true, err: ErrCNAMELoop},
+		{name: "nonexistent.test", ipv4: true, ipv6: true, err: ErrNotFound},
+		{name: "big.test", ipv4: true, addresses: []string{"192.0.2.10", "192.0.2.11"}},
+	}
+	for _, test := range tests {
+		r := newTestResolver(t, server, WithRecordTypes(test.ipv4, test.ipv6))
+		answer := r.Lookup(context.Background(), test.name)
+		if test.err != nil {
+			if !errors.Is(answer.Err, test.err) {
+				t.Errorf("Lookup(%q, A %v AAAA %v) error = %v, want %v", test.name, test.ipv4, test.ipv6, answer.Err, test.err)
+			}
+		} else if answer.Err != nil {
+			t.Errorf("Lookup(%q, A %v AAAA %v) error: %v", test.name, test.ipv4, test.ipv6, answer.Err)
+		}
+		if test.cnames != nil && !reflect.DeepEqual(answer.CNAMEs, test.cnames) {
+			t.Errorf("Lookup(%q) CNAMEs = %q, want %q", test.name, answer.CNAMEs, test.cnames)
+		}
+		sort.Strings(answer.Addresses)
+		if !reflect.DeepEqual(answer.Addresses, test.addresses) {
+			t.Errorf("Lookup(%q, A %v AAAA %v) addresses = %q, want %q", test.name, test.ipv4, test.ipv6, answer.Addresses, test.addresses)
+		}
+		if answer.Name != normalize(test.name) {
+			t.Errorf("Lookup(%q) name = %q, want %q", test.name, answer.Name, normalize(test.name))
+		}
+	}
+}
+
+func TestTruncatedRetriesOverTCP(t *testing.T) {
+	server := newStubServer(t, testZone)
+	r := newTestResolver(t, server, WithRecordTypes(true, false))
+	if answer := r.Lookup(context.Background(), "big.test"); answer.Err != nil {
+		t.Fatalf("Lookup error: %v", answer.Err)
+	}
+	if udp, tcp := server.count("udp", "big.test", dnsmessage.TypeA), server.count("tcp", "big.test", dnsmessage.TypeA); udp != 1 || tcp != 1 {
+		t.Errorf("queries over udp %d, tcp %d, want 1 each", udp, tcp)
+	}
+	if tcp := server.count("tcp", "host.test", dnsmessage.TypeA); tcp != 0 {
+		t.Errorf("untruncated answer retried over tcp")
+	}
+}
+
+func TestCache(t *testing.T) {
+	server := newStubServer(t, testZone)
+	r := newTestResolver(t, server, WithWorkers(8))
+	names := []string{"host.test", "HOST.test", "host.test.", "nonexistent.test", "nonexistent.test"}
+	for i := 0; i < 4; i++ {
+		names = append(names, names...)
+	}
+	answers := r.LookupAll(context.Background(), names)
+	for i, answer := range answers {
+		if answer.Name != normalize(names[i]) {
+			t.Fatalf("answer %d is for %q, want %q", i, answer.Name, normalize(names[i]))
+		}
+	}
+	for _, name := range []string{"host.test", "nonexistent.test"} {
+		for _, qtype := range []dnsmessage.Type{dnsmessage.TypeA, dnsmessage.TypeAAAA} {
+			if n := server.count("udp", name, qtype); n != 1 {
+				t.Errorf("%s %s queried %d times, want 1", name, qtype, n)
+			}
+		}
+	}
+}
+
+func TestCancelledLookupNotCached(t *testing.T) {
+	server := newStubServer(t, testZone)
+	r := newTestResolver(t, server)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if answer := r.Lookup(ctx, "host.test"); answer.Err == nil {
+		t.Fatal("cancelled lookup succeeded")
+	}
+	if answer := r.Lookup(context.Background(), "host.test"); answer.Err != nil {
+		t.Errorf("lookup after a cancelled one failed: %v", answer.Err)
+	}
+}
+
+func TestHosts(t *testing.T) {
+	answers := []Answer{
+		{Name: "a.test", Addresses: []string{"192.0.2.1", "2001:db8::1"}},
+		{Name: "b.test", Addresses: []string{"192.0.2.1"}},
+		{Name: "a.test", Addresses: []string{"192.0.2.1", "2001:db8::1"}},
+		{Name: "c.test", Err: ErrNotFound},
+	}
+	want := map[string][]string{
+		"192.0.2.1":   {"a.test", "b.test"},
+		"2001:db8::1": {"a.test"},
+	}
+	if got := Hosts(answers); !reflect.DeepEqual(got, want) {
+		t.Errorf("Hosts = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,344 @@
+// Package targets parses target specifications and expands them into the
+// hosts to scan. A specification is an IP address, a CIDR range, a dash range
+// such as 10.0.0.5-40 or 10.0.0.5-10.0.1.20, or a hostname. Ranges are never
+// materialized, hosts are generated while iterating.
+package targets
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Range is an inclusive range of addresses of a single family.
+type Range struct {
+	First netip.Addr
+	Last  netip.Addr
+}
+
+// Target is a parsed specification, either a hostname or an address range.
+type Target struct {
+	Host  string //Hostname, empty for addresses
+	Range Range  //Addresses of the target if Host is empty
+}
+
+// Parse parses a single target specification. The network and broadcast
+// addresses of IPv4 CIDR ranges larger than /31 are left out since no host
+// uses them.
+func Parse(spec string) (Target, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return Target{}, fmt.Errorf("empty target")
+	}
+	if strings.Contains(spec, "/") {
+		prefix, err := netip.ParsePrefix(spec)
+		if err != nil {
+			return Target{}, fmt.Errorf("invalid CIDR range %q", spec)
+		}
+		prefix = prefix.Masked()
+		r := Range{First: prefix.Addr(), Last: lastAddr(prefix)}
+		if prefix.Addr().Is4() && prefix.Bits() < 31 {
+			r.First, r.Last = r.First.Next(), r.Last.Prev()
+		}
+		return Target{Range: r}, nil
+	}
+	if addr, err := netip.ParseAddr(spec); err == nil {
+		addr = addr.Unmap()
+		return Target{Range: Range{First: addr, Last: addr}}, nil
+	}
+	if first, last, ok := strings.Cut(spec, "-"); ok {
+		if _, err := netip.ParseAddr(first); err == nil { //Hostnames may contain dashes too
+			r, err := parseRange(first, last)
+			if err != nil {
+				return Target{}, err
+			}
+			return Target{Range: r}, nil
+		}
+	}
+	if !IsHostname(spec) {
+		return Target{}, fmt.Errorf("invalid target %q", spec)
+	}
+	return Target{Host: strings.ToLower(strings.TrimSuffix(spec, "."))}, nil
+}
+
+// parseRange parses the two sides of a dash range. The last address may be
+// given in full or, for IPv4, as the last octet only.
+func parseRange(first_str, last_str string) (Range, error) {
+	first, err := netip.ParseAddr(first_str)
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid range start %q", first_str)
+	}
+	first = first.Unmap()
+	last, err := netip.ParseAddr(last_str)
+	if err != nil && first.Is4() && !strings.ContainsAny(last_str, ".:") {
+		octets := first.As4()
+		last, err = netip.ParseAddr(fmt.Sprintf("%d.%d.%d.%s", octets[0], octets[1], octets[2], last_str))
+	}
+	if err != nil {
+		return Range{}, fmt.Errorf("invalid range end %q", last_str)
+	}
+	last = last.Unmap()
+	if first.Is4() != last.Is4() || last.Less(first) {
+		return Range{}, fmt.Errorf("invalid range %s-%s", first_str, last_str)
+	}
+	return Range{First: first, Last: last}, nil
+}
+
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	bytes := prefix.Addr().AsSlice()
+	for bit := prefix.Bits(); bit < len(bytes)*8; bit++ {
+		bytes[bit/8] |= 0x80 >> (bit % 8)
+	}
+	addr, _ := netip.AddrFromSlice(bytes)
+	return addr
+}
+
+// IsHostname reports whether spec is a syntactically valid hostname.
+func IsHostname(spec string) bool {
+	spec = strings.TrimSuffix(spec, ".")
+	if spec == "" || len(spec) > 253 {
+		return false
+	}
+	labels := strings.Split(spec, ".")
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" { //Malformed address, top level domains are never numeric
+		return false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Split breaks a list of specifications separated by commas or whitespace.
+func Split(list string) []string {
+	return strings.FieldsFunc(list, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	})
+}
+
+// ReadFile reads specifications from a file, or from stdin if path is "-".
+// Entries are separated by newlines, commas or whitespace and everything after
+// a # is a comment.
+func ReadFile(path string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		r = file
+	}
+	var specs []string
+	lines := bufio.NewScanner(r)
+	for lines.Scan() {
+		line, _, _ := strings.Cut(lines.Text(), "#")
+		specs = append(specs, Split(line)...)
+	}
+	return specs, lines.Err()
+}
+
+// Set is an ordered collection of targets without duplicates. Hosts appear in
+// the order their specification was given, an address covered by an earlier
+// specification or by an exclusion is left out.
+type Set struct {
+	entries   []entry
+	truncated []string //IPv6 specifications cut down to the limit
+}
+
+// DefaultIPv6Limit is the number of addresses scanned from an IPv6 range when
+// no other limit is given. A /64 alone holds 2^64 addresses.
+const DefaultIPv6Limit = 1 << 16
+
+type entry struct {
+	host   string  //Hostname, empty for address ranges
+	ranges []Range //Addresses not covered by earlier entries or exclusions
+}
+
+// NewSet parses include and exclude specifications into a Set. Excluded
+// hostnames only match targets given by the same name. IPv6 ranges holding
+// more than ipv6_limit addresses are cut down to their first ipv6_limit
+// addresses left after exclusions, where hosts numbered by hand or by DHCPv6
+// usually live. A zero limit means DefaultIPv6Limit.
+func NewSet(include, exclude []string, ipv6_limit uint64) (*Set, error) {
+	if ipv6_limit == 0 {
+		ipv6_limit = DefaultIPv6Limit
+	}
+	var covered rangeSet
+	hosts := make(map[string]bool)
+	for _, spec := range exclude {
+		target, err := Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("exclude: %w", err)
+		}
+		if target.Host != "" {
+			hosts[target.Host] = true
+		} else {
+			covered.add(target.Range)
+		}
+	}
+
+	set := &Set{}
+	for _, spec := range include {
+		target, err := Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		if target.Host != "" {
+			if !hosts[target.Host] {
+				hosts[target.Host] = true
+				set.entries = append(set.entries, entry{host: target.Host})
+			}
+			continue
+		}
+		if target.Range.Last.Less(target.Range.First) { //CIDR range without usable hosts
+			continue
+		}
+		ranges := covered.subtract(target.Range)
+		if target.Range.First.Is6() {
+			var cut bool
+			if ranges, cut = limitRanges(ranges, ipv6_limit); cut {
+				set.truncated = append(set.truncated, spec)
+			}
+		}
+		if len(ranges) > 0 {
+			set.entries = append(set.entries, entry{ranges: ranges})
+		}
+		for _, r := range ranges {
+			covered.add(r)
+		}
+	}
+	return set, nil
+}
+
+// Each calls fn for every host of the set. Iteration stops early when fn
+// returns false.
+func (s *Set) Each(fn func(host string) bool) {
+	for _, e := range s.entries {
+		if e.host != "" {
+			if !fn(e.host) {
+				return
+			}
+			continue
+		}
+		for _, r := range e.ranges {
+			for addr := r.First; ; addr = addr.Next() {
+				if !fn(addr.String()) {
+					return
+				}
+				if addr == r.Last {
+					break
+				}
+			}
+		}
+	}
+}
+
+// Empty reports whether the set has no hosts.
+func (s *Set) Empty() bool {
+	return len(s.entries) == 0
+}
+
+// Truncated lists the IPv6 specifications that were cut down to the limit
+// given to NewSet.
+func (s *Set) Truncated() []string {
+	return s.truncated
+}
+
+// size returns the number of addresses in r, saturating at the maximum uint64.
+func (r Range) size() uint64 {
+	first, last := r.First.As16(), r.Last.As16()
+	first_hi, last_hi := binary.BigEndian.Uint64(first[:8]), binary.BigEndian.Uint64(last[:8])
+	first_lo, last_lo := binary.BigEndian.Uint64(first[8:]), binary.BigEndian.Uint64(last[8:])
+	diff_lo, borrow := bits.Sub64(last_lo, first_lo, 0)
+	if last_hi-first_hi-borrow > 0 || diff_lo == math.MaxUint64 {
+		return math.MaxUint64
+	}
+	return diff_lo + 1
+}
+
+// limitRanges keeps the first limit addresses of ranges and reports whether
+// any were cut.
+func limitRanges(ranges []Range, limit uint64) ([]Range, bool) {
+	for i, r := range ranges {
+		size := r.size()
+		if size > limit {
+			ranges[i].Last = advance(r.First, limit-1)
+			return ranges[:i+1], true
+		}
+		if limit -= size; limit == 0 {
+			return ranges[:i+1], i+1 < len(ranges)
+		}
+	}
+	return ranges, false
+}
+
+// advance returns the IPv6 address n addresses after addr.
+func advance(addr netip.Addr, n uint64) netip.Addr {
+	bytes := addr.As16()
+	lo, carry := bits.Add64(binary.BigEndian.Uint64(bytes[8:]), n, 0)
+	binary.BigEndian.PutUint64(bytes[8:], lo)
+	binary.BigEndian.PutUint64(bytes[:8], binary.BigEndian.Uint64(bytes[:8])+carry)
+	return netip.AddrFrom16(bytes)
+}
+
+// rangeSet is a sorted list of disjoint, non adjacent ranges.
+type rangeSet []Range
+
+// before reports whether a ends before b starts with a gap between them.
+func before(a, b Range) bool {
+	if a.Last.Is4() != b.First.Is4() {
+		return a.Last.Less(b.First)
+	}
+	next := a.Last.Next()
+	return next.IsValid() && next.Less(b.First)
+}
+
+// add inserts r, merging it with the ranges it overlaps or touches.
+func (rs *rangeSet) add(r Range) {
+	list := *rs
+	i := sort.Search(len(list), func(i int) bool { return !before(list[i], r) })
+	j := i
+	for j < len(list) && !before(r, list[j]) {
+		if list[j].First.Less(r.First) {
+			r.First = list[j].First
+		}
+		if r.Last.Less(list[j].Last) {
+			r.Last = list[j].Last
+		}
+		j++
+	}
+	merged := append(append(append(make(rangeSet, 0, len(list)-(j-i)+1), list[:i]...), r), list[j:]...)
+	*rs = merged
+}
+
+// subtract returns the parts of r not covered by the set, in order.
+func (rs rangeSet) subtract(r Range) []Range {
+	var parts []Range
+	cursor := r.First
+	i := sort.Search(len(rs), func(i int) bool { return !rs[i].Last.Less(r.First) })
+	for ; i < len(rs) && !r.Last.Less(rs[i].First); i++ {
+		if cursor.Less(rs[i].First) {
+			parts = append(parts, Range{First: cursor, Last: rs[i].First.Prev()})
+		}
+		if !rs[i].Last.Less(r.Last) {
+			return parts
+		}
+		cursor = rs[i].Last.Next()
+	}
+	return append(parts, Range{First: cursor, Last: r.Last})
+}
@@ -0,0 +1,311 @@
+package targets
+
+import (
+	"math"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		spec        string
+		host        string
+		first, last string
+		err         bool
+	}{
+		{spec: "10.0.0.0/24", first: "10.0.0.1", last: "10.0.0.254"},
+		{spec: "10.0.0.7/24", first: "10.0.0.1", last: "10.0.0.254"},
+		{spec: "10.0.0.0/31", first: "10.0.0.0", last: "10.0.0.1"},
+		{spec: "10.0.0.9/32", first: "10.0.0.9", last: "10.0.0.9"},
+		{spec: "0.0.0.0/0", first: "0.0.0.1", last: "255.255.255.254"},
+		{spec: "2001:db8::/126", first: "2001:db8::", last: "2001:db8::3"},
+		{spec: "10.0.0.5-40", first: "10.0.0.5", last: "10.0.0.40"},
+		{spec: "10.0.0.250-10.0.1.4", first: "10.0.0.250", last: "10.0.1.4"},
+		{spec: "2001:db8::1-2001:db8::ff", first: "2001:db8::1", last: "2001:db8::ff"},
+		{spec: "::ffff:10.0.0.1", first: "10.0.0.1", last: "10.0.0.1"},
+		{spec: " Example.COM. ", host: "example.com"},
+		{spec: "my-host", host: "my-host"},
+		{spec: "10.0.0.40-5", err: true},
+		{spec: "10.0.0.1-2001:db8::1", err: true},
+		{spec: "10.0.0.0/33", err: true},
+		{spec: "300.1.1.1", err: true},
+		{spec: "-bad.example", err: true},
+		{spec: "", err: true},
+	}
+	for _, test := range tests {
+		target, err := Parse(test.spec)
+		if test.err {
+			if err == nil {
+				t.Errorf("Parse(%q) = %+v, want error", test.spec, target)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", test.spec, err)
+			continue
+		}
+		if target.Host != test.host {
+			t.Errorf("Parse(%q) host = %q, want %q", test.spec, target.Host, test.host)
+		}
+		if test.host != "" {
+			continue
+		}
+		want := Range{First: netip.MustParseAddr(test.first), Last: netip.MustParseAddr(test.last)}
+		if target.Range != want {
+			t.Errorf("Parse(%q) range = %v-%v, want %v-%v", test.spec, target.Range.First, target.Range.Last, want.First, want.Last)
+		}
+	}
+}
+
+func hosts(t *testing.T, include, exclude []string, ipv6_limit uint64) []string {
+	t.Helper()
+	set, err := NewSet(include, exclude, ipv6_limit)
+	if err != nil {
+		t.Fatalf("NewSet(%q, %q) error: %v", include, exclude, err)
+	}
+	var list []string
+	set.Each(func(host string) bool {
+		list = append(list, host)
+		return true
+	})
+	return list
+}
+
+func TestSet(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{
+			name:    "adjacent",
+			include: []string{"10.0.0.1-2", "10.0.0.3-4"},
+			want:    []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+		},
+		{
+			name:    "overlapping",
+			include: []string{"10.0.0.1-3", "10.0.0.2-5"},
+			want:    []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5"},
+		},
+		{
+			name:    "contained later",
+			include: []string{"10.0.0.1-4", "10.0.0.2-3", "10.0.0.4"},
+			want:    []string{"10.0.0.1", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+		},
+		{
+			name:    "containing later",
+			include: []string{"10.0.0.3", "10.0.0.1-5"},
+			want:    []string{"10.0.0.3", "10.0.0.1", "10.0.0.2", "10.0.0.4", "10.0.0.5"},
+		},
+		{
+			name:    "bridging",
+			include: []string{"10.0.0.1", "10.0.0.5", "10.0.0.1-5"},
+			want:    []string{"10.0.0.1", "10.0.0.5", "10.0.0.2", "10.0.0.3", "10.0.0.4"},
+		},
+		{
+			name:    "excluded inside",
+			include: []string{"10.0.0.1-6"},
+			exclude: []string{"10.0.0.2-3", "10.0.0.5"},
+			want:    []string{"10.0.0.1", "10.0.0.4", "10.0.0.6"},
+		},
+		{
+			name:    "excluded edges",
+			include: []string{"10.0.0.1-4"},
+			exclude: []string{"10.0.0.0-1", "10.0.0.4-9"},
+			want:    []string{"10.0.0.2", "10.0.0.3"},
+		},
+		{
+			name:    "excluded entirely",
+			include: []string{"10.0.0.1-4", "10.0.0.9"},
+			exclude: []string{"10.0.0.0/29"},
+			want:    []string{"10.0.0.9"},
+		},
+		{
+			name:    "start of IPv4 space",
+			include: []string{"0.0.0.0-1", "0.0.0.0-2"},
+			exclude: []string{"0.0.0.1"},
+			want:    []string{"0.0.0.0", "0.0.0.2"},
+		},
+		{
+			name:    "end of IPv4 space",
+			include: []string{"255.255.255.254-255.255.255.255", "255.255.255.252-255.255.255.255"},
+			exclude: []string{"255.255.255.253"},
+			want:    []string{"255.255.255.254", "255.255.255.255", "255.255.255.252"},
+		},
+		{
+			name:    "end of IPv6 space",
+			include: []string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe/127", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffd-ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff"},
+			want:    []string{"ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffe", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:fffd"},
+		},
+		{
+			name:    "start of IPv6 space",
+			include: []string{"::1", "::/127"},
+			want:    []string{"::1", "::"},
+		},
+		{
+			name:    "families kept apart",
+			include: []string{"255.255.255.255", "::", "0.0.0.0", "::ffff:0.0.0.0"},
+			want:    []string{"255.255.255.255", "::", "0.0.0.0"},
+		},
+		{
+			name:    "hostnames",
+			include: []string{"a.example", "A.example.", "b.example", "c.example"},
+			exclude: []string{"b.example"},
+			want:    []string{"a.example", "c.example"},
+		},
+		{
+			name:    "CIDR without hosts",
+			include: []string{"10.0.0.0/30", "10.0.0.8"},
+			exclude: []string{"10.0.0.1-2"},
+			want:    []string{"10.0.0.8"},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := hosts(t, test.include, test.exclude, 0)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("hosts = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSetEmpty(t *testing.T) {
+	set, err := NewSet([]string{"10.0.0.1-4"}, []string{"10.0.0.0/24"}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !set.Empty() {
+		t.Error("set with every host excluded is not empty")
+	}
+}
+
+func TestIPv6Limit(t *testing.T) {
+	set, err := NewSet([]string{"2001:db8::/64", "2001:db8::2-2001:db8::5", "2001:db8:1::/126"}, nil, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"2001:db8::/64"}; !reflect.DeepEqual(set.Truncated(), want) {
+		t.Errorf("Truncated() = %q, want %q", set.Truncated(), want)
+	}
+	var got []string
+	set.Each(func(host string) bool {
+		got = append(got, host)
+		return true
+	})
+	want := []string{
+		"2001:db8::", "2001:db8::1", "2001:db8::2", "2001:db8::3", //First 4 of the /64
+		"2001:db8::4", "2001:db8::5", //Rest of the dash range
+		"2001:db8:1::", "2001:db8:1::1", "2001:db8:1::2", "2001:db8:1::3",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hosts = %q, want %q", got, want)
+	}
+}
+
+func TestIPv6LimitAfterExclusions(t *testing.T) {
+	tests := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		want      []string
+		truncated bool
+	}{
+		{
+			name:      "excluded addresses not counted",
+			include:   []string{"2001:db8::/64"},
+			exclude:   []string{"2001:db8::-2001:db8::2", "2001:db8::5"},
+			want:      []string{"2001:db8::3", "2001:db8::4", "2001:db8::6", "2001:db8::7"},
+			truncated: true,
+		},
+		{
+			name:    "exact fit after exclusions",
+			include: []string{"2001:db8::-2001:db8::4"},
+			exclude: []string{"2001:db8::1"},
+			want:    []string{"2001:db8::", "2001:db8::2", "2001:db8::3", "2001:db8::4"},
+		},
+		{
+			name:      "excluded start of a large range",
+			include:   []string{"2001:db8::/64"},
+			exclude:   []string{"2001:db8::/120"},
+			want:      []string{"2001:db8::100", "2001:db8::101", "2001:db8::102", "2001:db8::103"},
+			truncated: true,
+		},
+	}
+	for _, test := range tests {
+		set, err := NewSet(test.include, test.exclude, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if truncated := len(set.Truncated()) > 0; truncated != test.truncated {
+			t.Errorf("%s: truncated = %v, want %v", test.name, truncated, test.truncated)
+		}
+		if got := hosts(t, test.include, test.exclude, 4); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: hosts = %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestEachStops(t *testing.T) {
+	set, err := NewSet([]string{"10.0.0.0/16", "a.example"}, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	set.Each(func(string) bool {
+		count++
+		return count < 3
+	})
+	if count != 3 {
+		t.Errorf("Each called fn %d times after it returned false, want 3", count)
+	}
+}
+
+func TestSize(t *testing.T) {
+	tests := []struct {
+		first, last string
+		want        uint64
+	}{
+		{"10.0.0.1", "10.0.0.1", 1},
+		{"0.0.0.0", "255.255.255.255", 1 << 32},
+		{"2001:db8::", "2001:db8::ffff", 1 << 16},
+		{"::", "::ffff:ffff:ffff:fffe", math.MaxUint64},
+		{"::", "::ffff:ffff:ffff:ffff", math.MaxUint64},
+		{"::", "ffff:ffff:ffff:ffff:ffff:ffff:ffff:ffff", math.MaxUint64},
+		{"::ffff:ffff:ffff:ffff", "0:0:0:1::", 2},
+	}
+	for _, test := range tests {
+		r := Range{First: netip.MustParseAddr(test.first), Last: netip.MustParseAddr(test.last)}
+		if got := r.size(); got != test.want {
+			t.Errorf("size(%s-%s) = %d, want %d", test.first, test.last, got, test.want)
+		}
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	tests := []struct {
+		addr string
+		n    uint64
+		want string
+	}{
+		{"2001:db8::", 0, "2001:db8::"},
+		{"2001:db8::", 0xffff, "2001:db8::ffff"},
+		{"::ffff:ffff:ffff:ffff", 1, "0:0:0:1::"},
+		{"2001:db8::1", math.MaxUint64, "2001:db8:0:1::"},
+	}
+	for _, test := range tests {
+		if got := advance(netip.MustParseAddr(test.addr), test.n); got != netip.MustParseAddr(test.want) {
+			t.Errorf("advance(%s, %d) = %s, want %s", test.addr, test.n, got, test.want)
+		}
+	}
+}
+
+func TestReadSplit(t *testing.T) {
+	got := Split("10.0.0.1, example.com\t10.0.0.0/30\n\n::1")
+	want := []string{"10.0.0.1", "example.com", "10.0.0.0/30", "::1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Split = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+package scanner
+
+import (
+	"strings"
+
+	osdetect "github.com/efecankaya/go-port-scanner/internal/modules/os_detect"
+)
+
+// observeOS adds the evidence of an open port and the virtual host requests
+// made on it to the operating system guess of its host and returns the guess
+// so far, empty when the module is disabled or nothing hinted at a system.
+func (s *Scanner) observeOS(target_identify TargetResult, vhosts []TargetResult) string {
+	if !s.modules.OS || target_identify.State != StateOpen {
+		return ""
+	}
+	s.osMu.Lock()
+	host, ok := s.osHosts[target_identify.HostIP]
+	if !ok {
+		host = &osdetect.Host{}
+		s.osHosts[target_identify.HostIP] = host
+	}
+	s.osMu.Unlock()
+
+	var guess osdetect.Guess
+	for _, result := range append([]TargetResult{target_identify}, vhosts...) {
+		evidence := osdetect.Evidence{
+			Banner: result.Banner,
+			Server: headerValue(result.HttpResponseHeader, "Server"),
+			TTL:    result.TTL,
+			Window: result.TCPWindow,
+		}
+		if result.Service != nil {
+			evidence.Product = result.Service.Product
+			evidence.Version = result.Service.Version
+		}
+		guess = host.Add(evidence)
+	}
+	return guess.String()
+}
+
+// OperatingSystems returns the operating system guessed for every host of the
+// last Run with an open port that hinted at one. Each guess weighs the
+// evidence of all ports of the host.
+func (s *Scanner) OperatingSystems() map[string]OSGuess {
+	s.osMu.Lock()
+	defer s.osMu.Unlock()
+	guesses := make(map[string]OSGuess)
+	for ip, host := range s.osHosts {
+		if guess := host.Guess(); guess.Family != "" {
+			guesses[ip] = guess
+		}
+	}
+	return guesses
+}
+
+func headerValue(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,437 @@
+package scanner
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/efecankaya/go-port-scanner/internal/modules/banner"
+	pageinfo "github.com/efecankaya/go-port-scanner/internal/modules/page_info"
+	"github.com/efecankaya/go-port-scanner/internal/modules/service"
+	techfinder "github.com/efecankaya/go-port-scanner/internal/modules/tech_finder"
+	tlsprobe "github.com/efecankaya/go-port-scanner/internal/modules/tls_probe"
+	"github.com/valyala/fasthttp"
+)
+
+// Ports where clients are expected to speak first, these try TLS and HTTP
+// before the passive banner read since it would only wait for the timeout.
+var (
+	httpPorts = map[int]bool{80: true, 81: true, 591: true, 3000: true, 5000: true, 8000: true, 8008: true, 8080: true, 8081: true, 8088: true, 8888: true, 9000: true, 9090: true, 9200: true}
+	tlsPorts  = map[int]bool{443: true, 4443: true, 8443: true, 9443: true, 10443: true}
+)
+
+const clientHeader = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/122.0.0.0 Safari/537.36"
+
+// scanTarget probes a single host:port target. Targets that cannot be
+// connected to are returned as closed or filtered with the dial error kept.
+func (s *Scanner) scanTarget(ctx context.Context, target string) TargetResult {
+	target_identify := TargetResult{Protocol: "tcp"}
+	host, portStr, _ := net.SplitHostPort(target)
+	port, _ := strconv.Atoi(portStr)
+	target_identify.HostIP = host
+	target_identify.Port = port
+	if names := s.hostnames[host]; len(names) > 0 {
+		target_identify.Hostname = names[0]
+	}
+	if err := s.hosts.acquire(ctx, host); err != nil {
+		return target_identify
+	}
+	defer s.hosts.release(host)
+	if err := s.pace(ctx); err != nil {
+		return target_identify
+	}
+	dialer := net.Dialer{Timeout: s.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", target)
+	s.backoff.record(isTimeout(err))
+	if err != nil {
+		target_identify.State = classifyDialError(err)
+		target_identify.Error = err.Error()
+		return target_identify
+	}
+	target_identify.State = StateOpen
+
+	client_first := httpPorts[port] || tlsPorts[port]
+	if client_first {
+		conn.Close() //Port is open, requests below use their own connections
+		if s.detectClientFirst(ctx, target, tlsPorts[port], &target_identify) {
+			identifyService(&target_identify, nil, "")
+			target_identify.Error = ""
+			return target_identify
+		}
+		// Neither TLS nor HTTP, something else runs on the web port
+		conn = nil
+		if s.pace(ctx) == nil {
+			conn, _ = dialer.DialContext(ctx, "tcp", target)
+		}
+	}
+
+	// Grabbing banner
+	var raw_banner []byte
+	err = errors.New("banner module disabled")
+	if conn != nil {
+		if s.modules.Banner {
+			raw_banner, err = banner.Grab(conn, s.timeout, s.bannerLimit)
+		}
+		conn.Close()
+	}
+	if err == nil {
+		target_identify.Error = ""
+		setBanner(&target_identify, raw_banner, banner.PassiveProbe)
+		identifyService(&target_identify, raw_banner, "")
+		if target_identify.Service == nil || target_identify.Service.Method == "table" {
+			// Banner is not recognized, see if a probe gets a better answer
+			s.detectProbes(ctx, target, port, &target_identify)
+		}
+		return target_identify
+	}
+	// Silent service, it might be waiting for the client to speak first
+	if client_first || !s.detectClientFirst(ctx, target, true, &target_identify) {
+		s.detectProbes(ctx, target, port, &target_identify)
+	}
+	if target_identify.Service == nil {
+		identifyService(&target_identify, nil, "")
+	}
+	target_identify.Error = "" //Open ports only keep errors that explain their state
+	return target_identify
+}
+
+func isTimeout(err error) bool {
+	var net_err net.Error
+	return errors.As(err, &net_err) && net_err.Timeout()
+}
+
+// classifyDialError tells a closed port, one actively refusing connections,
+// apart from a filtered one that drops or rejects packets on the way.
+func classifyDialError(err error) string {
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) {
+		return StateClosed
+	}
+	return StateFiltered
+}
+
+// setBanner stores a response as the banner of target_identify along with the
+// probe that elicited it.
+func setBanner(target_identify *TargetResult, response []byte, probe string) {
+	target_identify.Banner = banner.Printable(response)
+	target_identify.BannerRaw = response
+	target_identify.BannerProbe = probe
+}
+
+// detectProbes sends the hellos of the probe library for port until one of
+// the responses matches a known service. Without a match the first response
+// is kept as the banner unless one was already grabbed. It reports whether any
+// probe got a response.
+func (s *Scanner) detectProbes(ctx context.Context, target string, port int, target_identify *TargetResult) bool {
+	if !s.modules.ServiceProbes {
+		return false
+	}
+	var first []byte
+	var first_probe string
+	for _, probe := range banner.ProbesFor(port) {
+		if s.pace(ctx) != nil {
+			break
+		}
+		response, err := banner.SendProbe(target, probe.Payload, s.timeout, s.bannerLimit)
+		if err != nil {
+			continue
+		}
+		if first == nil {
+			first, first_probe = response, probe.Name
+		}
+		if info, ok := service.Match(response, probe.Name); ok {
+			setBanner(target_identify, response, probe.Name)
+			target_identify.Service = &info
+			return true
+		}
+	}
+	if first == nil {
+		return false
+	}
+	if target_identify.Banner == "" {
+		setBanner(target_identify, first, first_probe)
+	}
+	if target_identify.Service == nil {
+		identifyService(target_identify, nil, "")
+	}
+	return true
+}
+
+// identifyService labels target_identify from what was collected so far,
+// falling back to the port table.
+func identifyService(target_identify *TargetResult, response []byte, probe string) {
+	if target_identify.HttpValid {
+		info := service.FromHTTP(target_identify.HttpResponseHeader, target_identify.TLS != nil)
+		target_identify.Service = &info
+		return
+	}
+	if len(response) > 0 {
+		if info, ok := service.Match(response, probe); ok {
+			target_identify.Service = &info
+			return
+		}
+	}
+	if info, ok := service.Lookup(target_identify.Port); ok {
+		target_identify.Service = &info
+	}
+}
+
+// detectClientFirst probes a silent service for TLS and plain HTTP, in the
+// order given by tls_first. The hostname of target_identify is sent as SNI and
+// Host header. It reports whether either protocol was detected.
+func (s *Scanner) detectClientFirst(ctx context.Context, target string, tls_first bool, target_identify *TargetResult) bool {
+	tryTLS := func() bool {
+		if !s.modules.TLS || s.pace(ctx) != nil {
+			return false
+		}
+		tls_info, err := tlsprobe.Probe(target, target_identify.Hostname, s.timeout)
+		if err != nil {
+			target_identify.Error = err.Error()
+			return false
+		}
+		target_identify.TLS = tls_info
+		target_identify.Error = ""
+		if s.pace(ctx) != nil || s.httpRequest("https", target, target_identify) != nil {
+			target_identify.Error = "" //TLS service without HTTP is still a result
+		}
+		return true
+	}
+	tryHTTP := func() bool {
+		if s.pace(ctx) != nil {
+			return false
+		}
+		if err := s.httpRequest("http", target, target_identify); err != nil {
+			return false
+		}
+		target_identify.Error = ""
+		return true
+	}
+
+	if tls_first {
+		return tryTLS() || tryHTTP()
+	}
+	return tryHTTP() || tryTLS()
+}
+
+// httpClient is implemented by both the shared client and the per address
+// clients of hostname requests.
+type httpClient interface {
+	DoTimeout(req *fasthttp.Request, resp *fasthttp.Response, timeout time.Duration) error
+}
+
+// hostClient returns the client sending requests for hostname to the web
+// server at target. Each address gets one client whose connections are reused
+// by all of its virtual hosts. A TLS connection stays bound to the server name
+// it was opened with, so over HTTPS there is one client per name.
+func (s *Scanner) hostClient(scheme, target, hostname string) *fasthttp.HostClient {
+	key := scheme + "://" + target
+	if scheme == "https" {
+		key += "/" + hostname
+	}
+	s.httpMu.Lock()
+	defer s.httpMu.Unlock()
+	client, ok := s.httpClients[key]
+	if !ok {
+		client = &fasthttp.HostClient{
+			Addr:      target,
+			IsTLS:     scheme == "https",
+			TLSConfig: &tls.Config{ServerName: hostname, InsecureSkipVerify: true},
+		}
+		s.httpClients[key] = client
+	}
+	return client
+}
+
+// httpRequest sends a GET request for / to target over scheme and fills the
+// HTTP fields of target_identify from the response. With a hostname set the
+// request still goes to target but names the hostname in the Host header and
+// SNI. Redirects are only followed while they stay on the same host.
+func (s *Scanner) httpRequest(scheme, target string, target_identify *TargetResult) error {
+	if !s.modules.HTTP {
+		return errors.New("http module disabled")
+	}
+	var client httpClient = s.client
+	timeout := s.timeout
+	url := scheme + "://" + target + "/"
+	if target_identify.Hostname != "" {
+		client = s.hostClient(scheme, target, target_identify.Hostname)
+		url = scheme + "://" + hostHeader(scheme, target, target_identify.Hostname) + "/"
+	}
+	req_target := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req_target)
+	req_target.SetRequestURI(url)
+	req_target.SetTimeout(timeout)
+	req_target.Header.Set("User-Agent", clientHeader)
+	resp_target := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp_target)
+
+	redirect_limit := 5
+	if err := doSameHost(client, req_target, resp_target, redirect_limit, timeout); err != nil {
+		//Handle error better
+		target_identify.Error = err.Error()
+		return err
+	}
+	url = req_target.URI().String() //Relative links resolve against the final page
+	//Client and server errors are still valid HTTP responses
+	target_identify.HttpStatusCode = resp_target.StatusCode()
+	//Gather headers from response
+	headers := make(map[string]string)
+	resp_target.Header.VisitAll(func(key, value []byte) {
+		headers[string(key)] = string(value)
+	})
+
+	//Gather cookies from response
+	cookies := make(map[string]string)
+	resp_target.Header.VisitAllCookie(func(key, value []byte) {
+		cookies[string(key)] = string(value)
+	})
+
+	responsePacket, err := io.ReadAll(bytes.NewReader(resp_target.Body()))
+	if err != nil {
+		//Handle error better
+		target_identify.Error = err.Error()
+		return err
+	}
+
+	target_identify.HttpValid = true
+	target_identify.HttpResponseHeader = headers
+	target_identify.HttpResponseBody = string(responsePacket)
+	target_identify.HttpResponseCookies = cookies
+	page := pageinfo.Parse(target_identify.HttpResponseBody)
+	target_identify.HttpTitle = page.Title
+	target_identify.HttpBodySHA256 = pageinfo.SHA256(responsePacket)
+	target_identify.HttpContentLength = len(responsePacket)
+	var favicons []int32
+	if s.modules.Favicon {
+		target_identify.Favicon = s.fetchFavicon(client, pageinfo.IconURL(url, page))
+		if target_identify.Favicon != nil {
+			favicons = append(favicons, target_identify.Favicon.MMH3)
+		}
+	}
+	if s.modules.TechFinder {
+		target_identify.Technologies = s.tech.Analyze(techfinder.Response{
+			Headers:  headers,
+			Cookies:  cookies,
+			Body:     target_identify.HttpResponseBody,
+			Favicons: favicons,
+		})
+	}
+	return nil
+}
+
+// fetchFavicon downloads the icon at url and hashes it. Error pages and HTML
+// served in place of a missing icon are ignored.
+func (s *Scanner) fetchFavicon(client httpClient, url string) *FaviconInfo {
+	if url == "" {
+		return nil
+	}
+	req_icon := fasthttp.AcquireRequest()
+	defer fasthttp.ReleaseRequest(req_icon)
+	req_icon.SetRequestURI(url)
+	req_icon.SetTimeout(s.timeout)
+	req_icon.Header.Set("User-Agent", clientHeader)
+	resp_icon := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp_icon)
+
+	redirect_limit := 3
+	if err := doSameHost(client, req_icon, resp_icon, redirect_limit, s.timeout); err != nil {
+		return nil
+	}
+	icon := resp_icon.Body()
+	content_type := strings.ToLower(string(resp_icon.Header.ContentType()))
+	if resp_icon.StatusCode() != fasthttp.StatusOK || len(icon) == 0 || strings.HasPrefix(content_type, "text/html") {
+		return nil
+	}
+	return &FaviconInfo{
+		URL:    req_icon.URI().String(),
+		MMH3:   pageinfo.FaviconHash(icon),
+		SHA256: pageinfo.SHA256(icon),
+		Size:   len(icon),
+	}
+}
+
+// doSameHost sends req and follows up to limit redirects as long as they keep
+// the scheme and host of req, which is left holding the URI of the final
+// response. A redirect that leaves the host, or fails, is returned as the
+// response itself.
+func doSameHost(client httpClient, req *fasthttp.Request, resp *fasthttp.Response, limit int, timeout time.Duration) error {
+	if err := client.DoTimeout(req, resp, timeout); err != nil {
+		return err
+	}
+	scheme, host := string(req.URI().Scheme()), string(req.URI().Host())
+	resp_redirect := fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseResponse(resp_redirect)
+	for redirects := 0; redirects < limit && fasthttp.StatusCodeIsRedirect(resp.StatusCode()); redirects++ {
+		location := resp.Header.Peek(fasthttp.HeaderLocation)
+		if len(location) == 0 {
+			break
+		}
+		previous := req.URI().String()
+		req.URI().UpdateBytes(location)
+		if string(req.URI().Scheme()) != scheme || !strings.EqualFold(string(req.URI().Host()), host) {
+			req.SetRequestURI(previous)
+			break
+		}
+		if err := client.DoTimeout(req, resp_redirect, timeout); err != nil {
+			req.SetRequestURI(previous)
+			break
+		}
+		resp_redirect.CopyTo(resp)
+	}
+	return nil
+}
+
+// hostHeader returns the Host header naming hostname on the port of target,
+// leaving out the default port of scheme.
+func hostHeader(scheme, target, hostname string) string {
+	_, port, _ := net.SplitHostPort(target)
+	if scheme == "http" && port == "80" || scheme == "https" && port == "443" {
+		return hostname
+	}
+	return net.JoinHostPort(hostname, port)
+}
+
+// scanVHosts repeats the TLS and HTTP detection of an open web port for the
+// other hostnames of its address and the virtual hosts given by WithVHosts.
+// Every virtual host gets its own result.
+func (s *Scanner) scanVHosts(ctx context.Context, target string, primary TargetResult) []TargetResult {
+	if primary.State != StateOpen || !primary.HttpValid && primary.TLS == nil {
+		return nil
+	}
+	if err := s.hosts.acquire(ctx, primary.HostIP); err != nil {
+		return nil
+	}
+	defer s.hosts.release(primary.HostIP)
+	seen := map[string]bool{primary.Hostname: true}
+	var results []TargetResult
+	for _, vhost := range append(append([]string(nil), s.hostnames[primary.HostIP]...), s.vhosts...) {
+		if seen[vhost] || ctx.Err() != nil {
+			continue
+		}
+		seen[vhost] = true
+		vhost_identify := TargetResult{HostIP: primary.HostIP, Hostname: vhost, Port: primary.Port, Protocol: primary.Protocol, State: StateOpen}
+		if s.pace(ctx) != nil {
+			break
+		}
+		scheme := "http"
+		if primary.TLS != nil {
+			scheme = "https"
+			if tls_info, err := tlsprobe.Probe(target, vhost, s.timeout); err == nil {
+				vhost_identify.TLS = tls_info //Certificates differ per SNI
+			}
+		}
+		if s.httpRequest(scheme, target, &vhost_identify) != nil && vhost_identify.TLS == nil {
+			continue //Nothing new to report
+		}
+		vhost_identify.Error = ""
+		identifyService(&vhost_identify, nil, "")
+		results = append(results, vhost_identify)
+	}
+	return results
+}
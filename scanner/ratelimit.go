@@ -0,0 +1,165 @@
+package scanner
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// rateLimiter spaces connection attempts evenly across all workers.
+type rateLimiter struct {
+	interval time.Duration //Time between two connections, zero for unlimited
+	mu       sync.Mutex
+	next     time.Time //Earliest time the next connection may start
+}
+
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l.interval <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	delay := l.next.Sub(now)
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+	return sleep(ctx, delay)
+}
+
+// hostLimiter caps the number of targets of a single host scanned at once.
+type hostLimiter struct {
+	limit int //Targets per host, zero for unlimited
+	mu    sync.Mutex
+	slots map[string]*hostSlot
+}
+
+type hostSlot struct {
+	users int           //Workers holding or waiting for the slot
+	sem   chan struct{} //Held by workers scanning the host
+}
+
+func (l *hostLimiter) acquire(ctx context.Context, host string) error {
+	if l.limit <= 0 {
+		return nil
+	}
+	l.mu.Lock()
+	if l.slots == nil {
+		l.slots = make(map[string]*hostSlot)
+	}
+	slot, ok := l.slots[host]
+	if !ok {
+		slot = &hostSlot{sem: make(chan struct{}, l.limit)}
+		l.slots[host] = slot
+	}
+	slot.users++
+	l.mu.Unlock()
+
+	select {
+	case slot.sem <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		l.leave(host, slot)
+		return ctx.Err()
+	}
+}
+
+func (l *hostLimiter) release(host string) {
+	if l.limit <= 0 {
+		return
+	}
+	l.mu.Lock()
+	slot := l.slots[host]
+	l.mu.Unlock()
+	<-slot.sem
+	l.leave(host, slot)
+}
+
+// leave drops a user of slot and forgets hosts nobody is scanning.
+func (l *hostLimiter) leave(host string, slot *hostSlot) {
+	l.mu.Lock()
+	slot.users--
+	if slot.users == 0 {
+		delete(l.slots, host)
+	}
+	l.mu.Unlock()
+}
+
+// backoff slows the scan down while most connection attempts time out, which
+// usually means a rate limit or an overloaded device on the way.
+type backoff struct {
+	enabled  bool
+	mu       sync.Mutex
+	samples  int           //Attempts since the last adjustment
+	timeouts int           //Timeouts since the last adjustment
+	delay    time.Duration //Extra delay before every connection
+}
+
+const (
+	backoffWindow = 32 //Attempts between adjustments
+	backoffMin    = 50 * time.Millisecond
+	backoffMax    = 5 * time.Second
+)
+
+func (b *backoff) record(timeout bool) {
+	if !b.enabled {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.samples++
+	if timeout {
+		b.timeouts++
+	}
+	if b.samples < backoffWindow {
+		return
+	}
+	ratio := float64(b.timeouts) / float64(b.samples)
+	switch {
+	case ratio > 0.5:
+		b.delay = min(max(b.delay*2, backoffMin), backoffMax)
+	case ratio < 0.1:
+		b.delay /= 2
+		if b.delay < backoffMin {
+			b.delay = 0
+		}
+	}
+	b.samples, b.timeouts = 0, 0
+}
+
+func (b *backoff) current() time.Duration {
+	if !b.enabled {
+		return 0
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.delay
+}
+
+// pace blocks until the next connection of the calling worker may start.
+func (s *Scanner) pace(ctx context.Context) error {
+	delay := s.delay + s.backoff.current()
+	if s.jitter > 0 {
+		delay += time.Duration(rand.Int63n(int64(s.jitter)))
+	}
+	if err := sleep(ctx, delay); err != nil {
+		return err
+	}
+	return s.limiter.wait(ctx)
+}
+
+func sleep(ctx context.Context, delay time.Duration) error {
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
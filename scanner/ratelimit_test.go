@@ -0,0 +1,146 @@
+package scanner
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	l := &rateLimiter{interval: 20 * time.Millisecond}
+	start := time.Now()
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.wait(context.Background()); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Errorf("6 connections took %v, want at least 5 intervals", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l.next = time.Now().Add(time.Hour)
+	if err := l.wait(ctx); err == nil {
+		t.Error("wait with a cancelled context succeeded")
+	}
+	if err := (&rateLimiter{}).wait(ctx); err != nil {
+		t.Errorf("unlimited wait = %v, want no wait at all", err)
+	}
+}
+
+func TestHostLimiter(t *testing.T) {
+	l := &hostLimiter{limit: 2}
+	var active, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := l.acquire(context.Background(), "192.0.2.1"); err != nil {
+				t.Error(err)
+				return
+			}
+			n := active.Add(1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			active.Add(-1)
+			l.release("192.0.2.1")
+		}()
+	}
+	wg.Wait()
+	if peak.Load() != 2 {
+		t.Errorf("%d targets of one host scanned at once, want 2", peak.Load())
+	}
+	if len(l.slots) != 0 {
+		t.Errorf("%d idle hosts kept", len(l.slots))
+	}
+
+	//Other hosts are not held up by a busy one
+	l.acquire(context.Background(), "192.0.2.1")
+	l.acquire(context.Background(), "192.0.2.1")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, "192.0.2.2"); err != nil {
+		t.Errorf("acquire of another host = %v", err)
+	}
+	if err := l.acquire(ctx, "192.0.2.1"); err == nil {
+		t.Error("acquire past the limit succeeded")
+	}
+	l.release("192.0.2.2")
+	l.release("192.0.2.1")
+	l.release("192.0.2.1")
+	if len(l.slots) != 0 {
+		t.Errorf("%d hosts kept after a cancelled acquire", len(l.slots))
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	b := &backoff{enabled: true}
+	record := func(timeouts, total int) {
+		for i := 0; i < total; i++ {
+			b.record(i < timeouts)
+		}
+	}
+	record(20, backoffWindow)
+	if got := b.current(); got != backoffMin {
+		t.Errorf("delay after mostly timeouts = %v, want %v", got, backoffMin)
+	}
+	record(20, backoffWindow)
+	if got := b.current(); got != 2*backoffMin {
+		t.Errorf("delay after two bad windows = %v, want doubled", got)
+	}
+	for i := 0; i < 10; i++ {
+		record(backoffWindow, backoffWindow)
+	}
+	if got := b.current(); got != backoffMax {
+		t.Errorf("delay = %v, want capped at %v", got, backoffMax)
+	}
+	record(8, backoffWindow) //Between the thresholds
+	if got := b.current(); got != backoffMax {
+		t.Errorf("delay = %v after a mixed window, want unchanged", got)
+	}
+	for i := 0; i < 10; i++ {
+		record(0, backoffWindow)
+	}
+	if got := b.current(); got != 0 {
+		t.Errorf("delay = %v after recovering, want 0", got)
+	}
+
+	disabled := &backoff{}
+	for i := 0; i < backoffWindow; i++ {
+		disabled.record(true)
+	}
+	if disabled.current() != 0 {
+		t.Error("disabled backoff slowed down")
+	}
+}
+
+func TestPace(t *testing.T) {
+	s := &Scanner{delay: 30 * time.Millisecond, jitter: 20 * time.Millisecond}
+	start := time.Now()
+	if err := s.pace(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond || elapsed > time.Second {
+		t.Errorf("paced for %v, want the delay plus up to the jitter", elapsed)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.pace(ctx); err == nil {
+		t.Error("pace with a cancelled context succeeded")
+	}
+}
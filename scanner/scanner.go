@@ -0,0 +1,545 @@
+// Package scanner implements a TCP port scanner that identifies the services
+// listening on open ports.
+package scanner
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/efecankaya/go-port-scanner/internal/modules/banner"
+	"github.com/efecankaya/go-port-scanner/internal/modules/discovery"
+	osdetect "github.com/efecankaya/go-port-scanner/internal/modules/os_detect"
+	pageinfo "github.com/efecankaya/go-port-scanner/internal/modules/page_info"
+	"github.com/efecankaya/go-port-scanner/internal/modules/service"
+	synscan "github.com/efecankaya/go-port-scanner/internal/modules/syn_scan"
+	techfinder "github.com/efecankaya/go-port-scanner/internal/modules/tech_finder"
+	tlsprobe "github.com/efecankaya/go-port-scanner/internal/modules/tls_probe"
+	"github.com/efecankaya/go-port-scanner/internal/targets"
+	"github.com/valyala/fasthttp"
+)
+
+type TargetResult struct {
+	HostIP              string            `json:"host"`                          //IP address of the target
+	Hostnames           []string          `json:"hostnames,omitempty"`           //Domain names that resolved to HostIP
+	Hostname            string            `json:"hostname,omitempty"`            //Host header and SNI of the HTTP and TLS fields
+	Port                int               `json:"port"`                          //Port number of the target
+	Protocol            string            `json:"protocol"`                      //Transport protocol, tcp or udp
+	State               string            `json:"state"`                         //Port state, open, closed or filtered
+	Banner              string            `json:"banner,omitempty"`              //Banner of the target, non printable bytes escaped
+	BannerRaw           []byte            `json:"banner_raw,omitempty"`          //Banner bytes as received, base64 in JSON
+	BannerProbe         string            `json:"banner_probe,omitempty"`        //Probe that elicited the banner, empty for a passive read
+	Service             *ServiceInfo      `json:"service,omitempty"`             //Identified service
+	TLS                 *TLSInfo          `json:"tls,omitempty"`                 //TLS handshake details if the port speaks TLS
+	TTL                 int               `json:"ttl,omitempty"`                 //IP TTL of the SYN scan reply
+	TCPWindow           int               `json:"tcp_window,omitempty"`          //TCP window of the SYN scan reply
+	HttpValid           bool              `json:"http_valid"`                    //If contains valid http response
+	HttpStatusCode      int               `json:"http_status,omitempty"`         //HTTP status code
+	HttpResponseHeader  map[string]string `json:"http_headers,omitempty"`        //HTTP headers
+	HttpResponseCookies map[string]string `json:"http_cookies,omitempty"`        //HTTP cookies
+	HttpResponseBody    string            `json:"http_body,omitempty"`           //HTTP response body
+	HttpTitle           string            `json:"http_title,omitempty"`          //Title of the HTML page
+	HttpBodySHA256      string            `json:"http_body_sha256,omitempty"`    //Hex encoded SHA-256 of the body
+	HttpContentLength   int               `json:"http_content_length,omitempty"` //Bytes of the body
+	Favicon             *FaviconInfo      `json:"favicon,omitempty"`             //Icon of the site
+	Technologies        []Technology      `json:"technologies,omitempty"`        //Technologies identified from the HTTP response
+	OperatingSystem     string            `json:"operating_system,omitempty"`    //Operating system of the target
+	Error               string            `json:"error,omitempty"`               //Error that left the port closed or filtered
+}
+
+// Result is the value streamed by Scanner.Run.
+type Result = TargetResult
+
+type (
+	ServiceInfo     = service.ServiceInfo
+	TLSInfo         = tlsprobe.TLSInfo
+	CertificateInfo = tlsprobe.CertificateInfo
+	Technology      = techfinder.Technology
+	FaviconInfo     = pageinfo.Favicon
+	OSGuess         = osdetect.Guess
+)
+
+const (
+	StateOpen     = "open"     //Connection established
+	StateClosed   = "closed"   //Connection refused, nothing is listening
+	StateFiltered = "filtered" //No answer or unreachable, likely firewalled
+
+	StateOpenFiltered = "open|filtered" //UDP port that did not answer, open or dropped
+)
+
+// Modules selects the detection steps run against open ports.
+type Modules struct {
+	Banner        bool //Passive banner read
+	TLS           bool //TLS handshake probe
+	HTTP          bool //HTTP(S) request
+	ServiceProbes bool //Protocol specific service probes
+	TechFinder    bool //Technology fingerprinting of HTTP responses
+	Favicon       bool //Favicon download and hashing
+	OS            bool //Operating system inference from the other results
+}
+
+// AllModules enables every detection step.
+var AllModules = Modules{Banner: true, TLS: true, HTTP: true, ServiceProbes: true, TechFinder: true, Favicon: true, OS: true}
+
+// Scanner scans every combination of its targets and ports. It is configured
+// through Options passed to New.
+type Scanner struct {
+	targets     []string
+	cidrs       []string
+	exclude     []string
+	ipv6Limit   uint64              //Addresses scanned per IPv6 range
+	hostnames   map[string][]string //Domain names by the address they resolved to
+	vhosts      []string            //Virtual hosts requested on every web port
+	set         *targets.Set        //Deduplicated hosts of targets and cidrs
+	ports       []int
+	concurrency int
+	timeout     time.Duration
+	modules     Modules
+	showClosed  bool
+	udp         bool
+	synMode     bool             //SYN scan requested
+	syn         *synscan.Scanner //Raw socket of a running SYN scan
+	synErr      error            //Why the SYN scan fell back to connect
+	client      *fasthttp.Client
+	bannerLimit int                //Bytes read from a banner or probe response
+	techRules   string             //Technology rules file, empty for the bundled rules
+	tech        *techfinder.Engine //Technology fingerprinting rules
+
+	discover  bool              //Ping hosts and skip the dead ones
+	pinger    *discovery.Pinger //Liveness probes of the current run
+	hostsUp   atomic.Int64      //Hosts found alive by discovery
+	hostsDown atomic.Int64      //Hosts skipped by discovery
+
+	osMu    sync.Mutex                //Guards osHosts
+	osHosts map[string]*osdetect.Host //Operating system evidence by host of the current run
+
+	httpMu      sync.Mutex                      //Guards httpClients
+	httpClients map[string]*fasthttp.HostClient //Clients of hostname requests by scheme, address and server name
+
+	resumeOffset  uint64    //Generated pairs already handed out by a previous run
+	resumePending []string  //Unfinished pairs of a previous run
+	progress      *progress //Progress of the current run
+
+	delay   time.Duration //Pause before every connection of a worker
+	jitter  time.Duration //Random extra pause up to this long
+	limiter rateLimiter   //Global connections per second
+	hosts   hostLimiter   //Targets per host scanned at once
+	backoff backoff       //Adaptive slowdown on timeouts
+}
+
+type Option func(*Scanner)
+
+// WithTargets adds targets to scan: IP addresses, hostnames, CIDR ranges or
+// dash ranges such as 10.0.0.5-40. Hosts given more than once are scanned
+// once.
+func WithTargets(targets ...string) Option {
+	return func(s *Scanner) { s.targets = append(s.targets, targets...) }
+}
+
+// WithCIDR adds CIDR ranges to scan. Addresses are generated while scanning
+// rather than up front.
+func WithCIDR(cidrs ...string) Option {
+	return func(s *Scanner) { s.cidrs = append(s.cidrs, cidrs...) }
+}
+
+// WithExclude leaves the hosts of the given targets out of the scan. It
+// accepts the same specifications as WithTargets.
+func WithExclude(targets ...string) Option {
+	return func(s *Scanner) { s.exclude = append(s.exclude, targets...) }
+}
+
+// WithHostnames records which domain names resolved to each address, results
+// of those addresses list them.
+func WithHostnames(hostnames map[string][]string) Option {
+	return func(s *Scanner) {
+		if s.hostnames == nil {
+			s.hostnames = make(map[string][]string)
+		}
+		for address, names := range hostnames {
+			s.hostnames[address] = append(s.hostnames[address], names...)
+		}
+	}
+}
+
+// WithVHosts requests every open HTTP or TLS port again for each of the given
+// virtual hosts. Each virtual host that answers is reported as its own result,
+// as are the other domain names of the address given by WithHostnames.
+func WithVHosts(vhosts ...string) Option {
+	return func(s *Scanner) { s.vhosts = append(s.vhosts, vhosts...) }
+}
+
+// WithIPv6Limit caps the addresses scanned from a single IPv6 range, larger
+// ranges are cut down to their first n addresses. It defaults to 65536.
+func WithIPv6Limit(n int) Option {
+	return func(s *Scanner) {
+		if n > 0 {
+			s.ipv6Limit = uint64(n)
+		}
+	}
+}
+
+// WithPorts sets the ports scanned on every target.
+func WithPorts(ports ...int) Option {
+	return func(s *Scanner) { s.ports = append(s.ports, ports...) }
+}
+
+// WithConcurrency sets the number of targets scanned in parallel.
+func WithConcurrency(n int) Option {
+	return func(s *Scanner) { s.concurrency = n }
+}
+
+// WithTimeout sets the timeout of every connection, read and request.
+func WithTimeout(timeout time.Duration) Option {
+	return func(s *Scanner) { s.timeout = timeout }
+}
+
+// WithModules selects the detection steps run against open ports.
+func WithModules(modules Modules) Option {
+	return func(s *Scanner) { s.modules = modules }
+}
+
+// WithClosed makes Run also report closed, filtered and open|filtered ports.
+func WithClosed(show bool) Option {
+	return func(s *Scanner) { s.showClosed = show }
+}
+
+// WithUDP scans UDP instead of TCP ports, sending protocol specific payloads
+// where they are known.
+func WithUDP(udp bool) Option {
+	return func(s *Scanner) { s.udp = udp }
+}
+
+// WithSYN scans TCP ports with half-open SYN probes over a raw socket. It
+// needs root or CAP_NET_RAW, without them Run falls back to a connect scan and
+// SYNError tells why.
+func WithSYN(syn bool) Option {
+	return func(s *Scanner) { s.synMode = syn }
+}
+
+// WithDiscovery pings every host before scanning its ports and skips those
+// that do not answer. Hosts are pinged with ICMP echo requests, TCP
+// connections to common ports and, on local subnets, ARP.
+func WithDiscovery(enabled bool) Option {
+	return func(s *Scanner) { s.discover = enabled }
+}
+
+// WithBannerLimit sets how many bytes are read from a banner or probe
+// response. It defaults to 4096.
+func WithBannerLimit(limit int) Option {
+	return func(s *Scanner) { s.bannerLimit = limit }
+}
+
+// WithTechRules replaces the bundled technology fingerprinting rules with the
+// rules file at path.
+func WithTechRules(path string) Option {
+	return func(s *Scanner) { s.techRules = path }
+}
+
+// WithRate limits the connections opened per second across all workers.
+func WithRate(per_second int) Option {
+	return func(s *Scanner) {
+		if per_second > 0 {
+			s.limiter.interval = time.Second / time.Duration(per_second)
+		}
+	}
+}
+
+// WithHostConcurrency caps the targets of a single host scanned at once.
+func WithHostConcurrency(n int) Option {
+	return func(s *Scanner) { s.hosts.limit = n }
+}
+
+// WithDelay makes every worker pause before each connection for delay plus a
+// random duration up to jitter.
+func WithDelay(delay, jitter time.Duration) Option {
+	return func(s *Scanner) { s.delay, s.jitter = delay, jitter }
+}
+
+// WithAdaptiveBackoff slows the scan down while most connections time out and
+// speeds it up again once they recover.
+func WithAdaptiveBackoff(enabled bool) Option {
+	return func(s *Scanner) { s.backoff.enabled = enabled }
+}
+
+// New creates a Scanner. Without options it scans ports 1-1024 using 10
+// workers, a one second timeout and every module.
+func New(options ...Option) (*Scanner, error) {
+	s := &Scanner{
+		concurrency: 10,
+		timeout:     time.Second,
+		modules:     AllModules,
+		bannerLimit: banner.DefaultLimit,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	if len(s.ports) == 0 {
+		for port := 1; port <= 1024; port++ {
+			s.ports = append(s.ports, port)
+		}
+	}
+	for _, port := range s.ports {
+		if port <= 0 || port > 65535 {
+			return nil, errors.New("port out of range: " + strconv.Itoa(port))
+		}
+	}
+	set, err := targets.NewSet(append(append([]string(nil), s.targets...), s.cidrs...), s.exclude, s.ipv6Limit)
+	if err != nil {
+		return nil, err
+	}
+	s.set = set
+	if s.concurrency <= 0 {
+		return nil, errors.New("concurrency must be positive")
+	}
+	if s.delay < 0 || s.jitter < 0 {
+		return nil, errors.New("delay and jitter cannot be negative")
+	}
+	if s.timeout <= 0 {
+		return nil, errors.New("timeout must be positive")
+	}
+	if s.bannerLimit <= 0 {
+		return nil, errors.New("banner limit must be positive")
+	}
+	s.tech = techfinder.Default()
+	if s.techRules != "" {
+		if s.tech, err = techfinder.Load(s.techRules); err != nil {
+			return nil, fmt.Errorf("loading technology rules: %w", err)
+		}
+	}
+	s.client = &fasthttp.Client{
+		TLSConfig: &tls.Config{InsecureSkipVerify: true}, //Certificates are recorded, not verified
+	}
+	return s, nil
+}
+
+// Run starts the scan and streams results as targets finish. Targets are
+// generated lazily and handed to a fixed pool of workers, so memory use does
+// not depend on the size of the scanned range. The channel is closed once
+// every target is scanned or ctx is cancelled.
+func (s *Scanner) Run(ctx context.Context) (<-chan Result, error) {
+	if len(s.targets) == 0 && len(s.cidrs) == 0 {
+		return nil, errors.New("no targets given")
+	}
+	if s.set.Empty() {
+		return nil, errors.New("every target is excluded")
+	}
+
+	s.progress = newProgress(s.resumeOffset)
+	s.syn, s.synErr = nil, nil
+	if s.synMode && !s.udp {
+		s.syn, s.synErr = synscan.New()
+	}
+	s.pinger = nil
+	s.hostsUp.Store(0)
+	s.hostsDown.Store(0)
+	s.osHosts = make(map[string]*osdetect.Host)
+	s.httpClients = make(map[string]*fasthttp.HostClient)
+	if s.discover {
+		s.pinger = discovery.New(s.timeout, nil)
+	}
+	jobs := make(chan job, s.concurrency)
+	results := make(chan Result, s.concurrency)
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go s.generate(ctx, jobs, &wg)
+	for i := 0; i < s.concurrency; i++ { //Start workers
+		wg.Add(1)
+		go s.worker(ctx, jobs, results, &wg)
+	}
+	go func() {
+		wg.Wait()
+		if s.syn != nil {
+			s.syn.Close()
+		}
+		if s.pinger != nil {
+			s.pinger.Close()
+		}
+		close(results)
+	}()
+	return results, nil
+}
+
+type job struct {
+	id     uint64 //Progress identifier
+	target string //host:port pair
+}
+
+// generate feeds the pending pairs of a resumed scan and then every host:port
+// pair to jobs, skipping those handed out by a previous run. Hosts are taken in
+// blocks and each port is sent to every host of the block before moving on,
+// which spreads the load instead of hammering one host at a time. With
+// discovery the pairs of dead hosts still count as handed out, so offsets stay
+// valid whichever hosts answer.
+func (s *Scanner) generate(ctx context.Context, jobs chan<- job, wg *sync.WaitGroup) {
+	defer wg.Done()
+	defer close(jobs)
+	pending := make([]job, 0, len(s.resumePending))
+	for _, target := range s.resumePending {
+		pending = append(pending, job{s.progress.add(target), target})
+	}
+	for _, j := range pending {
+		select {
+		case jobs <- j:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	skip := s.resumeOffset
+	s.eachBlock(func(hosts []string) bool {
+		size := uint64(len(hosts) * len(s.ports))
+		if skip >= size {
+			skip -= size
+			return true
+		}
+		alive, ok := s.discoverBlock(ctx, hosts)
+		if !ok {
+			return false
+		}
+		for i := skip; i < size; i++ {
+			if !alive[i%uint64(len(hosts))] {
+				s.progress.generated()
+				continue
+			}
+			host, port := hosts[i%uint64(len(hosts))], s.ports[i/uint64(len(hosts))]
+			target := net.JoinHostPort(host, strconv.Itoa(port))
+			j := job{s.progress.add(target), target}
+			select {
+			case jobs <- j:
+				s.progress.generated()
+			case <-ctx.Done():
+				s.progress.done(j.id) //Never handed out, it is regenerated on resume
+				return false
+			}
+		}
+		skip = 0
+		return true
+	})
+}
+
+const hostBlock = 64 //Hosts interleaved by generate
+
+// discoverBlock pings the hosts of a block in parallel and reports which are
+// alive, in block order. Every host is alive without discovery. It reports
+// false if ctx was cancelled before all hosts answered.
+func (s *Scanner) discoverBlock(ctx context.Context, hosts []string) ([]bool, bool) {
+	alive := make([]bool, len(hosts))
+	if s.pinger == nil {
+		for i := range alive {
+			alive[i] = true
+		}
+		return alive, true
+	}
+	sem := make(chan struct{}, s.concurrency)
+	var wg sync.WaitGroup
+	for i, host := range hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			if s.pace(ctx) != nil {
+				return
+			}
+			alive[i], _ = s.pinger.Alive(ctx, host)
+		}(i, host)
+	}
+	wg.Wait()
+	if ctx.Err() != nil {
+		return nil, false
+	}
+	for _, up := range alive {
+		if up {
+			s.hostsUp.Add(1)
+		} else {
+			s.hostsDown.Add(1)
+		}
+	}
+	return alive, true
+}
+
+// Truncated lists the IPv6 targets that hold more addresses than the limit set
+// by WithIPv6Limit and are only partly scanned.
+func (s *Scanner) Truncated() []string {
+	return s.set.Truncated()
+}
+
+// SkippedTechRules lists the technology patterns left out of the rules because
+// they do not compile.
+func (s *Scanner) SkippedTechRules() []string {
+	return s.tech.Skipped()
+}
+
+// HostsDiscovered reports how many hosts discovery found alive and dead during
+// the last Run. Both are zero without discovery.
+func (s *Scanner) HostsDiscovered() (up, down int) {
+	return int(s.hostsUp.Load()), int(s.hostsDown.Load())
+}
+
+// eachBlock calls fn with consecutive blocks of up to hostBlock hosts. The
+// slice is reused between calls.
+func (s *Scanner) eachBlock(fn func(hosts []string) bool) {
+	hosts := make([]string, 0, hostBlock)
+	stopped := false
+	s.eachHost(func(host string) bool {
+		hosts = append(hosts, host)
+		if len(hosts) == hostBlock {
+			stopped = !fn(hosts)
+			hosts = hosts[:0]
+		}
+		return !stopped
+	})
+	if !stopped && len(hosts) > 0 {
+		fn(hosts)
+	}
+}
+
+// eachHost calls fn for every target host, expanding ranges on the fly.
+func (s *Scanner) eachHost(fn func(host string) bool) {
+	s.set.Each(fn)
+}
+
+func (s *Scanner) worker(ctx context.Context, jobs <-chan job, results chan<- Result, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for j := range jobs {
+		if ctx.Err() != nil {
+			return
+		}
+		var target_identify TargetResult
+		var vhosts []TargetResult
+		switch {
+		case s.udp:
+			target_identify = s.scanUDP(ctx, j.target)
+		case s.syn != nil:
+			target_identify = s.scanSYN(ctx, j.target)
+		default:
+			target_identify = s.scanTarget(ctx, j.target)
+			vhosts = s.scanVHosts(ctx, j.target, target_identify)
+		}
+		if ctx.Err() != nil {
+			return //Cancelled mid-scan, the result cannot be trusted
+		}
+		if target_identify.State != StateOpen && !s.showClosed {
+			s.progress.done(j.id)
+			continue
+		}
+		operating_system := s.observeOS(target_identify, vhosts)
+		for _, result := range append([]TargetResult{target_identify}, vhosts...) {
+			result.Hostnames = s.hostnames[result.HostIP]
+			result.OperatingSystem = operating_system
+			select {
+			case results <- result:
+			case <-ctx.Done():
+				return
+			}
+		}
+		s.progress.done(j.id)
+	}
+}
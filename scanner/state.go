@@ -0,0 +1,126 @@
+package scanner
+
+import (
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+)
+
+// State records which host:port pairs of a scan remain so an interrupted scan
+// can be continued. Pairs are produced in a fixed order; Offset
+// counts how many of them were handed out and Pending lists those among them
+// that never finished.
+type State struct {
+	Targets   []string            `json:"targets"`              //Hosts of the scan
+	CIDRs     []string            `json:"cidrs"`                //CIDR ranges of the scan
+	Exclude   []string            `json:"exclude,omitempty"`    //Targets left out of the scan
+	IPv6Limit uint64              `json:"ipv6_limit,omitempty"` //Addresses scanned per IPv6 range
+	Hostnames map[string][]string `json:"hostnames,omitempty"`  //Domain names by the address they resolved to
+	VHosts    []string            `json:"vhosts,omitempty"`     //Virtual hosts requested on every web port
+	Ports     []int               `json:"ports"`                //Ports of the scan
+	UDP       bool                `json:"udp,omitempty"`        //Ports are UDP
+	Offset    uint64              `json:"offset"`               //Pairs handed out in generation order
+	Pending   []string            `json:"pending"`              //Handed out pairs that did not finish
+}
+
+// WithResume continues the scan recorded in state. Its targets and ports
+// replace any given through other options.
+func WithResume(state State) Option {
+	return func(s *Scanner) {
+		s.targets = state.Targets
+		s.cidrs = state.CIDRs
+		s.exclude = state.Exclude
+		s.ipv6Limit = state.IPv6Limit
+		s.hostnames = state.Hostnames
+		s.vhosts = state.VHosts
+		s.ports = state.Ports
+		s.udp = state.UDP
+		s.resumeOffset = state.Offset
+		s.resumePending = state.Pending
+	}
+}
+
+// State reports the progress of the last Run. It is complete once the result
+// channel has been closed.
+func (s *Scanner) State() State {
+	state := State{Targets: s.targets, CIDRs: s.cidrs, Exclude: s.exclude, IPv6Limit: s.ipv6Limit, Hostnames: s.hostnames, VHosts: s.vhosts, Ports: s.ports, UDP: s.udp}
+	if s.progress == nil {
+		state.Offset = s.resumeOffset
+		state.Pending = s.resumePending
+		return state
+	}
+	state.Offset, state.Pending = s.progress.snapshot()
+	return state
+}
+
+// Save writes the state to path as JSON.
+func (state State) Save(path string) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadState reads a state written by State.Save.
+func LoadState(path string) (State, error) {
+	var state State
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return state, err
+	}
+	err = json.Unmarshal(data, &state)
+	return state, err
+}
+
+// progress tracks the pairs handed to workers that have not finished yet.
+type progress struct {
+	mu       sync.Mutex
+	offset   uint64            //Generated pairs handed out
+	next_id  uint64            //Identifier of the next job
+	inflight map[uint64]string //Unfinished jobs by identifier
+}
+
+func newProgress(offset uint64) *progress {
+	return &progress{offset: offset, inflight: make(map[uint64]string)}
+}
+
+// add registers a job before it is handed out.
+func (p *progress) add(target string) uint64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	id := p.next_id
+	p.next_id++
+	p.inflight[id] = target
+	return id
+}
+
+// generated counts a generated job as handed out.
+func (p *progress) generated() {
+	p.mu.Lock()
+	p.offset++
+	p.mu.Unlock()
+}
+
+// done marks a job as finished.
+func (p *progress) done(id uint64) {
+	p.mu.Lock()
+	delete(p.inflight, id)
+	p.mu.Unlock()
+}
+
+func (p *progress) snapshot() (uint64, []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ids := make([]uint64, 0, len(p.inflight))
+	for id := range p.inflight {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	pending := make([]string, 0, len(ids))
+	for _, id := range ids {
+		pending = append(pending, p.inflight[id])
+	}
+	return p.offset, pending
+}
@@ -0,0 +1,151 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStateSaveLoad(t *testing.T) {
+	state := State{
+		Targets:   []string{"192.0.2.1", "www.example.com"},
+		CIDRs:     []string{"198.51.100.0/30"},
+		Exclude:   []string{"198.51.100.2"},
+		IPv6Limit: 256,
+		Hostnames: map[string][]string{"192.0.2.1": {"www.example.com"}},
+		VHosts:    []string{"admin.example.com"},
+		Ports:     []int{22, 80, 443},
+		UDP:       true,
+		Offset:    7,
+		Pending:   []string{"192.0.2.1:80", "198.51.100.1:22"},
+	}
+	path := filepath.Join(t.TempDir(), "resume.json")
+	if err := state.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, state) {
+		t.Errorf("loaded %+v, want %+v", loaded, state)
+	}
+	if _, err := LoadState(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("LoadState of a missing file succeeded")
+	}
+}
+
+func TestProgress(t *testing.T) {
+	p := newProgress(10)
+	first := p.add("192.0.2.1:22")
+	p.generated()
+	second := p.add("192.0.2.1:80")
+	p.generated()
+	third := p.add("192.0.2.2:22")
+	p.done(second)
+	offset, pending := p.snapshot()
+	if offset != 12 || !reflect.DeepEqual(pending, []string{"192.0.2.1:22", "192.0.2.2:22"}) {
+		t.Errorf("snapshot = %d %q, want 12 with the unfinished pairs in order", offset, pending)
+	}
+	p.done(first)
+	p.done(third)
+	if _, pending := p.snapshot(); len(pending) != 0 {
+		t.Errorf("pending = %q after every job finished", pending)
+	}
+}
+
+// TestResume interrupts a scan of loopback ports and continues it from its
+// state, every port must be reported exactly once over both runs.
+func TestResume(t *testing.T) {
+	var ports []int
+	open := make(map[int]bool)
+	for i := 0; i < 40; i++ {
+		listener, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ports = append(ports, listener.Addr().(*net.TCPAddr).Port)
+		if i%2 == 0 {
+			open[ports[i]] = true
+			defer listener.Close()
+		} else {
+			listener.Close() //Closed port
+		}
+	}
+	options := []Option{
+		WithTargets("127.0.0.1"),
+		WithPorts(ports...),
+		WithConcurrency(2),
+		WithTimeout(time.Second),
+		WithModules(Modules{}),
+		WithClosed(true),
+		WithDiscovery(false),
+	}
+
+	seen := make(map[int]int)
+	check := func(result Result) {
+		seen[result.Port]++
+		if want := map[bool]string{true: StateOpen, false: StateClosed}[open[result.Port]]; result.State != want {
+			t.Errorf("port %d is %s, want %s", result.Port, result.State, want)
+		}
+	}
+	s, err := New(options...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	results, err := s.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for result := range results {
+		check(result)
+		if len(seen) == 5 {
+			cancel()
+		}
+	}
+	cancel()
+	state := s.State()
+	if state.Offset == 0 || state.Offset >= uint64(len(ports)) {
+		t.Fatalf("interrupted after %d of %d pairs, want a partial scan", state.Offset, len(ports))
+	}
+
+	path := filepath.Join(t.TempDir(), "resume.json")
+	if err := state.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if state, err = LoadState(path); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := New(append(options, WithResume(state))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	results, err = resumed.Run(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for result := range results {
+		check(result)
+	}
+
+	var missing, repeated []int
+	for _, port := range ports {
+		switch seen[port] {
+		case 0:
+			missing = append(missing, port)
+		case 1:
+		default:
+			repeated = append(repeated, port)
+		}
+	}
+	if len(missing) > 0 || len(repeated) > 0 {
+		t.Errorf("ports missing %v, reported twice %v", missing, repeated)
+	}
+	if final := resumed.State(); final.Offset != uint64(len(ports)) || len(final.Pending) != 0 {
+		t.Errorf("final state offset %d pending %q, want every pair done", final.Offset, final.Pending)
+	}
+}
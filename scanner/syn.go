@@ -0,0 +1,52 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/efecankaya/go-port-scanner/internal/modules/service"
+)
+
+// scanSYN classifies a single host:port target with a half-open SYN probe.
+// Only the port table labels open ports since no connection is made. Targets
+// the raw socket cannot reach fall back to a connect scan.
+func (s *Scanner) scanSYN(ctx context.Context, target string) TargetResult {
+	host, portStr, _ := net.SplitHostPort(target)
+	port, _ := strconv.Atoi(portStr)
+	ip := net.ParseIP(host)
+	if ip == nil || ip.To4() == nil {
+		return s.scanTarget(ctx, target)
+	}
+	target_identify := TargetResult{HostIP: host, Port: port, Protocol: "tcp"}
+	if err := s.hosts.acquire(ctx, host); err != nil {
+		return target_identify
+	}
+	defer s.hosts.release(host)
+	if err := s.pace(ctx); err != nil {
+		return target_identify
+	}
+
+	reply, err := s.syn.Probe(ctx, ip, port, s.timeout)
+	if err != nil {
+		target_identify.State = StateFiltered
+		target_identify.Error = err.Error()
+		return target_identify
+	}
+	s.backoff.record(reply.State == StateFiltered)
+	target_identify.State = reply.State
+	target_identify.TTL = reply.TTL
+	target_identify.TCPWindow = reply.Window
+	if reply.State == StateOpen {
+		if info, ok := service.Lookup(port); ok {
+			target_identify.Service = &info
+		}
+	}
+	return target_identify
+}
+
+// SYNError reports why a SYN scan requested through WithSYN fell back to a
+// connect scan during the last Run, nil if it did not.
+func (s *Scanner) SYNError() error {
+	return s.synErr
+}
@@ -0,0 +1,46 @@
+package scanner
+
+import (
+	"context"
+	"net"
+	"strconv"
+
+	"github.com/efecankaya/go-port-scanner/internal/modules/service"
+	udpprobe "github.com/efecankaya/go-port-scanner/internal/modules/udp_probe"
+)
+
+const udpRetries = 1 //Extra attempts per payload, datagrams get lost
+
+// scanUDP probes a single host:port target over UDP with the payload of its
+// protocol.
+func (s *Scanner) scanUDP(ctx context.Context, target string) TargetResult {
+	target_identify := TargetResult{Protocol: "udp"}
+	host, portStr, _ := net.SplitHostPort(target)
+	port, _ := strconv.Atoi(portStr)
+	target_identify.HostIP = host
+	target_identify.Port = port
+	if err := s.hosts.acquire(ctx, host); err != nil {
+		return target_identify
+	}
+	defer s.hosts.release(host)
+	if err := s.pace(ctx); err != nil {
+		return target_identify
+	}
+
+	udp_result := udpprobe.Probe(ctx, target, s.timeout, udpRetries)
+	s.backoff.record(udp_result.State == udpprobe.StateOpenFiltered)
+	target_identify.State = udp_result.State
+	if udp_result.Err != nil {
+		target_identify.Error = udp_result.Err.Error()
+	}
+	if udp_result.State != StateOpen {
+		return target_identify
+	}
+	setBanner(&target_identify, udp_result.Response, udp_result.Probe)
+	if udp_result.Service != "" {
+		target_identify.Service = &ServiceInfo{Name: udp_result.Service, Confidence: service.ConfidenceProbe, Method: "probe"}
+	} else if info, ok := service.Lookup(port); ok {
+		target_identify.Service = &info
+	}
+	return target_identify
+}